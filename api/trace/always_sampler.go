@@ -14,28 +14,18 @@
 
 package trace
 
-import (
-	"go.opentelemetry.io/api/core"
-)
-
 const (
 	alwaysSamplerDescription = "AlwaysSampleSampler"
 )
 
-var alwaysSampleDecision = Decision{Sampled: true}
+var alwaysSampleDecision = Decision{Result: RecordAndSample}
 
 type alwaysSampleSampler struct{}
 
 // ShouldSample implements Sampler interface.
-// It always returns a Decision with Sampled value set to true
+// It always returns a Decision with Result set to RecordAndSample
 // and with Attributes set to an empty slice.
-func (as alwaysSampleSampler) ShouldSample(
-	_ core.SpanContext,
-	_ bool,
-	_ core.TraceID,
-	_ uint64,
-	_ string,
-) Decision {
+func (as alwaysSampleSampler) ShouldSample(_ SamplingParameters) Decision {
 	return alwaysSampleDecision
 }
 
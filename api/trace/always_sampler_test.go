@@ -18,14 +18,11 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
-
-	"go.opentelemetry.io/api/core"
 )
 
 func TestShouldSample(t *testing.T) {
-	gotD := AlwaysSampleSampler().ShouldSample(
-		core.SpanContext{}, false, core.TraceID{}, 0, "span")
-	wantD := Decision{Sampled: true}
+	gotD := AlwaysSampleSampler().ShouldSample(SamplingParameters{Name: "span"})
+	wantD := Decision{Result: RecordAndSample}
 	if diff := cmp.Diff(wantD, gotD); diff != "" {
 		t.Errorf("Decision: +got, -want%v", diff)
 	}
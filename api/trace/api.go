@@ -104,6 +104,14 @@ type SpanOptions struct {
 	StartTime   time.Time
 	Reference   Reference
 	RecordEvent bool
+
+	// Sampler overrides the sampler of the SDK for the span if it is
+	// non-nil.
+	Sampler Sampler
+
+	// DeferSampling postpones the sampling decision until the span context
+	// is first read or the span ends.
+	DeferSampling bool
 }
 
 // Reference is used to establish relationship between newly created span and the
@@ -168,6 +176,27 @@ func WithRecordEvents() SpanOption {
 	}
 }
 
+// WithSampler makes the SDK consult s instead of its default sampler to
+// decide whether the span is recorded and sampled. Children of the span
+// follow its decision if the default sampler respects the sampling decision
+// of local parents, as the parent based samplers do.
+func WithSampler(s Sampler) SpanOption {
+	return func(o *SpanOptions) {
+		o.Sampler = s
+	}
+}
+
+// WithDeferredSampling postpones the sampling decision for the span until
+// its span context is first read, for example to start a child span or to
+// inject it into a request, or until the span ends. The sampler then sees
+// the name and attributes set in the meantime. The SDK records the span
+// until the decision is made.
+func WithDeferredSampling() SpanOption {
+	return func(o *SpanOptions) {
+		o.DeferSampling = true
+	}
+}
+
 // ChildOf. TODO: do we need this?.
 func ChildOf(sc core.SpanContext) SpanOption {
 	return func(o *SpanOptions) {
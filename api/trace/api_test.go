@@ -26,3 +26,27 @@ func TestGlobalTracer(t *testing.T) {
 		t.Fatalf("Failed to get tracer\n")
 	}
 }
+
+type namedTracer struct {
+	NoopTracer
+	name, version string
+}
+
+type testProvider struct{}
+
+func (testProvider) GetTracer(name, version string) Tracer {
+	return namedTracer{name: name, version: version}
+}
+
+func TestGlobalProvider(t *testing.T) {
+	if _, ok := GlobalProvider().GetTracer("lib", "1.0").(NoopTracer); !ok {
+		t.Fatalf("Default provider does not return the global tracer\n")
+	}
+
+	SetGlobalProvider(testProvider{})
+	defer SetGlobalProvider(globalTracerProvider{})
+	tracer, ok := GlobalProvider().GetTracer("lib", "1.0").(namedTracer)
+	if !ok || tracer.name != "lib" || tracer.version != "1.0" {
+		t.Fatalf("Failed to get named tracer from global provider, got %#v\n", tracer)
+	}
+}
@@ -21,6 +21,8 @@ import "sync/atomic"
 // install a default tracer w/ resources.
 var global atomic.Value
 
+var globalProvider atomic.Value
+
 // GlobalTracer return tracer registered with global registry.
 // If no tracer is registered then an instance of noop Tracer is returned.
 func GlobalTracer() Tracer {
@@ -34,3 +36,24 @@ func GlobalTracer() Tracer {
 func SetGlobalTracer(t Tracer) {
 	global.Store(t)
 }
+
+// GlobalProvider returns the provider registered with global registry.
+// If no provider is registered then a provider that returns the global
+// tracer for every name is returned.
+func GlobalProvider() Provider {
+	if p, ok := globalProvider.Load().(providerHolder); ok {
+		return p.provider
+	}
+	return globalTracerProvider{}
+}
+
+// SetGlobalProvider sets provided provider as a global provider.
+func SetGlobalProvider(p Provider) {
+	globalProvider.Store(providerHolder{provider: p})
+}
+
+// providerHolder allows providers of different concrete types to be
+// stored in globalProvider.
+type providerHolder struct {
+	provider Provider
+}
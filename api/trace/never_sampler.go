@@ -14,28 +14,18 @@
 
 package trace
 
-import (
-	"go.opentelemetry.io/api/core"
-)
-
 const (
 	neverSamplerDescription = "NeverSampleSampler"
 )
 
-var neverSampledecision = Decision{Sampled: false}
+var neverSampledecision = Decision{Result: Drop}
 
 type neverSampleSampler struct{}
 
 // ShouldSample implements Sampler interface.
-// It always returns a Decision with Sampled value set to false
+// It always returns a Decision with Result set to Drop
 // and with Attributes set to an empty slice.
-func (ns neverSampleSampler) ShouldSample(
-	_ core.SpanContext,
-	_ bool,
-	_ core.TraceID,
-	_ uint64,
-	_ string,
-) Decision {
+func (ns neverSampleSampler) ShouldSample(_ SamplingParameters) Decision {
 	return neverSampledecision
 }
 
@@ -18,14 +18,11 @@ import (
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
-
-	"go.opentelemetry.io/api/core"
 )
 
 func TestNeverSamperShouldSample(t *testing.T) {
-	gotD := NeverSampleSampler().ShouldSample(
-		core.SpanContext{}, false, core.TraceID{}, 0, "span")
-	wantD := Decision{Sampled: false}
+	gotD := NeverSampleSampler().ShouldSample(SamplingParameters{Name: "span"})
+	wantD := Decision{Result: Drop}
 	if diff := cmp.Diff(wantD, gotD); diff != "" {
 		t.Errorf("Decision: +got, -want%v", diff)
 	}
@@ -0,0 +1,45 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+// Provider provides access to the tracers of instrumentation libraries.
+type Provider interface {
+	// GetTracer returns the Tracer used by the instrumentation library
+	// identified by name and version, e.g. the import path of the
+	// instrumenting package and its release. Implementations should
+	// record name and version on the spans started by the Tracer.
+	GetTracer(name, version string) Tracer
+}
+
+// NoopProvider is an implementation of Provider that returns noop
+// tracers.
+type NoopProvider struct{}
+
+var _ Provider = NoopProvider{}
+
+// GetTracer returns noop implementation of Tracer.
+func (NoopProvider) GetTracer(name, version string) Tracer {
+	return NoopTracer{}
+}
+
+// globalTracerProvider is the Provider used when no global Provider is
+// set. It hands out the global tracer, so that instrumentation libraries
+// using named tracers keep working for applications which only set a
+// global tracer.
+type globalTracerProvider struct{}
+
+func (globalTracerProvider) GetTracer(name, version string) Tracer {
+	return GlobalTracer()
+}
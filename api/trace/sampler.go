@@ -16,17 +16,13 @@ package trace
 
 import "go.opentelemetry.io/api/core"
 
+// Sampler decides whether a span is recorded and whether it is sampled,
+// that is exported. SDKs consult the sampler whenever a span is started.
 type Sampler interface {
 	// ShouldSample returns a Decision that contains a decision whether to sample
 	// or not sample the span to be created. Decision is based on a Sampler specific
 	// algorithm that takes into account one or more input parameters.
-	ShouldSample(
-		sc core.SpanContext,
-		remote bool,
-		traceID core.TraceID,
-		spanID uint64,
-		spanName string,
-	) Decision
+	ShouldSample(SamplingParameters) Decision
 
 	// Description returns of the sampler. It contains its name or short description
 	// and its configured properties.
@@ -34,11 +30,54 @@ type Sampler interface {
 	Description() string
 }
 
+// SamplingParameters contains the values passed to a Sampler.
+type SamplingParameters struct {
+	// ParentContext is the span context of the parent span. It is empty
+	// for root spans.
+	ParentContext   core.SpanContext
+	TraceID         core.TraceID
+	SpanID          uint64
+	Name            string
+	HasRemoteParent bool
+
+	// Attributes are the attributes the span is started with.
+	Attributes []core.KeyValue
+
+	// TracerName and TracerVersion identify the instrumentation library
+	// whose tracer starts the span.
+	TracerName    string
+	TracerVersion string
+}
+
+// SamplingResult is the outcome of a sampling decision.
+type SamplingResult uint8
+
+const (
+	// Drop means that the span is neither recorded nor sampled.
+	Drop SamplingResult = iota
+	// RecordOnly means that the span is recorded, so that span processors
+	// see it, but it is not sampled.
+	RecordOnly
+	// RecordAndSample means that the span is recorded and sampled.
+	RecordAndSample
+)
+
 type Decision struct {
-	// Sampled is set true if the span should be sampled.
-	Sampled bool
+	// Result tells whether the span is recorded and sampled.
+	Result SamplingResult
 
-	// Attributes provides insight into Sample	r's decision process.
+	// Attributes provides insight into Sampler's decision process.
 	// It could be empty slice or nil if no attributes are recorded by the sampler.
+	// They are added to the span if it is recorded.
 	Attributes []core.KeyValue
 }
+
+// Sampled reports whether the span should be sampled.
+func (d Decision) Sampled() bool {
+	return d.Result == RecordAndSample
+}
+
+// Recorded reports whether the span should be recorded.
+func (d Decision) Recorded() bool {
+	return d.Result != Drop
+}
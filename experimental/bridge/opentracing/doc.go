@@ -28,6 +28,11 @@
 // bridge tracer and then passing the chosen OpenTelemetry tracer to
 // the SetOpenTelemetryTracer() function of the bridge tracer.
 //
+// To get the tracer of choice from a tracer provider, such as the
+// globally registered one, use the NewTracerPairWithProvider()
+// function instead. It requests the tracer named TracerName, so the
+// spans started through the bridge are attributed to it.
+//
 // Bridge tracer also allows the user to install a warning handler
 // through the SetWarningHandler() function. The warning handler will
 // be called when there is some misbehavior of the OpenTelemetry
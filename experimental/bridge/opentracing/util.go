@@ -18,6 +18,10 @@ import (
 	oteltrace "go.opentelemetry.io/api/trace"
 )
 
+// TracerName is the name of the instrumentation library the bridge
+// requests its tracer under, see NewTracerPairWithProvider.
+const TracerName = "go.opentelemetry.io/experimental/bridge/opentracing"
+
 // NewTracerPair is a utility function that creates a BridgeTracer
 // that forwards the calls to the WrapperTracer that wraps the passed
 // tracer.
@@ -27,3 +31,11 @@ func NewTracerPair(tracer oteltrace.Tracer) (*BridgeTracer, *WrapperTracer) {
 	bridgeTracer.SetOpenTelemetryTracer(wrapperTracer)
 	return bridgeTracer, wrapperTracer
 }
+
+// NewTracerPairWithProvider is a utility function that creates a
+// BridgeTracer and a WrapperTracer like NewTracerPair does, for the
+// tracer named TracerName of the passed provider. Pass
+// oteltrace.GlobalProvider() to use the globally registered provider.
+func NewTracerPairWithProvider(provider oteltrace.Provider) (*BridgeTracer, *WrapperTracer) {
+	return NewTracerPair(provider.GetTracer(TracerName, ""))
+}
@@ -0,0 +1,56 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package opentracing
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/sdk/trace"
+)
+
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []*sdktrace.SpanData
+}
+
+func (r *spanRecorder) ExportSpan(sd *sdktrace.SpanData) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, sd)
+}
+
+func TestNewTracerPairWithProvider(t *testing.T) {
+	var r spanRecorder
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithConfig(sdktrace.Config{DefaultSampler: sdktrace.AlwaysSample()}),
+		sdktrace.WithSyncer(&r),
+	)
+	otTracer, otelTracer := NewTracerPairWithProvider(provider)
+
+	otTracer.StartSpan("ot").Finish()
+	_, span := otelTracer.Start(context.Background(), "otel")
+	span.End()
+
+	if len(r.spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(r.spans))
+	}
+	for _, sd := range r.spans {
+		if sd.TracerName != TracerName {
+			t.Errorf("span %q: got tracer name %q, want %q", sd.Name, sd.TracerName, TracerName)
+		}
+	}
+}
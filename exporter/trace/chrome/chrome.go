@@ -0,0 +1,401 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chrome
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/api/core"
+	"go.opentelemetry.io/sdk/resource"
+	"go.opentelemetry.io/sdk/trace"
+)
+
+// Grouping selects how spans are distributed over the tracks of the
+// trace viewer.
+type Grouping int
+
+const (
+	// GroupByTrace shows every trace as a separate process whose threads
+	// are lanes of properly nested spans. This is the default grouping.
+	GroupByTrace Grouping = iota
+
+	// GroupByLane shows all spans in a single process whose threads are
+	// lanes of properly nested spans, similar to goroutines.
+	GroupByLane
+)
+
+// Options are the options to be used when initializing a Chrome
+// trace-event exporter.
+type Options struct {
+	// Writer is the destination of the trace-event JSON document.
+	// Required.
+	Writer io.Writer
+
+	// Grouping selects how spans are assigned to tracks.
+	// Default is GroupByTrace.
+	Grouping Grouping
+
+	// LaneRetention is how long the placement of a span in its lane is
+	// remembered, measured from the latest end time of the exported spans.
+	// Older spans are forgotten to bound the memory of long running
+	// exporters, as is a trace of GroupByTrace once all its spans are
+	// forgotten. A span overlapping a forgotten one may then be placed on
+	// a lane it does not nest properly with, and a late span of a
+	// forgotten trace is shown as a new process.
+	// Default is one minute.
+	LaneRetention time.Duration
+
+	// OnError is the hook to be called when the document cannot be
+	// written.
+	// If no custom hook is set, errors are logged.
+	// Optional.
+	OnError func(err error)
+}
+
+// Exporter is an implementation of trace.Exporter that writes spans as
+// Chrome trace events.
+type Exporter struct {
+	grouping  Grouping
+	retention time.Duration
+	onError   func(err error)
+
+	mu      sync.Mutex
+	w       io.Writer
+	started bool
+	closed  bool
+	nextPid int
+	pids    map[core.TraceID]int
+	procs   map[int]*process
+
+	// latest is the latest end time of the exported spans and pruned the
+	// time the lanes were last pruned at.
+	latest, pruned time.Time
+}
+
+// process holds the lanes of a single trace-viewer process.
+type process struct {
+	traceID core.TraceID
+	lanes   [][]interval
+}
+
+type interval struct {
+	start, end time.Time
+}
+
+// event is a single entry of the traceEvents array.
+type event struct {
+	Name  string                 `json:"name"`
+	Cat   string                 `json:"cat,omitempty"`
+	Ph    string                 `json:"ph"`
+	Ts    float64                `json:"ts"`
+	Dur   *float64               `json:"dur,omitempty"`
+	Pid   int                    `json:"pid"`
+	Tid   int                    `json:"tid"`
+	Scope string                 `json:"s,omitempty"`
+	Args  map[string]interface{} `json:"args,omitempty"`
+}
+
+var _ trace.Exporter = (*Exporter)(nil)
+
+const defaultLaneRetention = time.Minute
+
+// NewExporter returns a trace.Exporter implementation that writes the
+// collected spans to o.Writer in the Chrome trace-event format. Close
+// must be called to complete the document.
+func NewExporter(o Options) (*Exporter, error) {
+	if o.Writer == nil {
+		return nil, errors.New("missing writer for Chrome trace-event exporter")
+	}
+	if o.Grouping != GroupByTrace && o.Grouping != GroupByLane {
+		return nil, fmt.Errorf("unknown Chrome trace-event grouping: %d", o.Grouping)
+	}
+	onError := o.OnError
+	if onError == nil {
+		onError = func(err error) {
+			log.Printf("Error when writing Chrome trace events: %v", err)
+		}
+	}
+	retention := o.LaneRetention
+	if retention <= 0 {
+		retention = defaultLaneRetention
+	}
+	return &Exporter{
+		grouping:  o.Grouping,
+		retention: retention,
+		onError:   onError,
+		w:         o.Writer,
+		pids:      make(map[core.TraceID]int),
+		procs:     make(map[int]*process),
+	}, nil
+}
+
+// ExportSpan writes a complete event for the span and an instant event for
+// every message event of the span.
+func (e *Exporter) ExportSpan(data *trace.SpanData) {
+	e.mu.Lock()
+	errs := e.exportSpan(data)
+	e.mu.Unlock()
+	e.report(errs)
+}
+
+// exportSpan writes the events of the span and returns the errors to be
+// reported. e.mu must be held.
+func (e *Exporter) exportSpan(data *trace.SpanData) []error {
+	if e.closed {
+		return nil
+	}
+
+	pid, tid, errs := e.track(data)
+	dur := micros(data.EndTime.Sub(data.StartTime))
+	args := attributeArgs(data.Attributes)
+	args["traceId"] = data.SpanContext.TraceIDString()
+	args["spanId"] = data.SpanContext.SpanIDString()
+	if data.ParentSpanID != 0 {
+		args["parentSpanId"] = fmt.Sprintf("%.16x", data.ParentSpanID)
+	}
+	args["status"] = data.Status.String()
+	if err := e.write(event{
+		Name: data.Name,
+		Cat:  "span",
+		Ph:   "X",
+		Ts:   timestamp(data.StartTime),
+		Dur:  &dur,
+		Pid:  pid,
+		Tid:  tid,
+		Args: args,
+	}); err != nil {
+		errs = append(errs, err)
+	}
+	for _, ev := range data.MessageEvents {
+		if err := e.write(event{
+			Name:  ev.Message,
+			Cat:   "event",
+			Ph:    "i",
+			Ts:    timestamp(ev.Time),
+			Pid:   pid,
+			Tid:   tid,
+			Scope: "t",
+			Args:  attributeArgs(ev.Attributes),
+		}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// report passes errs to the OnError hook. e.mu must not be held, so that
+// the hook may use the exporter.
+func (e *Exporter) report(errs []error) {
+	for _, err := range errs {
+		e.onError(err)
+	}
+}
+
+// Close completes the JSON document. Spans exported after Close are
+// discarded. Close does not close the underlying writer.
+func (e *Exporter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	if !e.started {
+		_, err := io.WriteString(e.w, "{\"traceEvents\":[")
+		if err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(e.w, "\n],\"displayTimeUnit\":\"ms\"}\n")
+	return err
+}
+
+// track returns the process and thread the span is shown on. New tracks
+// are named with metadata events, whose write errors are returned. e.mu
+// must be held.
+func (e *Exporter) track(data *trace.SpanData) (pid, tid int, errs []error) {
+	if data.EndTime.After(e.latest) {
+		e.latest = data.EndTime
+		e.prune()
+	}
+	pid = 1
+	if e.grouping == GroupByTrace {
+		var ok bool
+		if pid, ok = e.pids[data.SpanContext.TraceID]; !ok {
+			e.nextPid++
+			pid = e.nextPid
+			e.pids[data.SpanContext.TraceID] = pid
+		}
+	}
+	p, ok := e.procs[pid]
+	if !ok {
+		p = &process{traceID: data.SpanContext.TraceID}
+		e.procs[pid] = p
+		name := "spans"
+		if e.grouping == GroupByTrace {
+			name = "trace " + data.SpanContext.TraceIDString()
+		}
+		if err := e.writeMetadata("process_name", pid, 0, name); err != nil {
+			errs = append(errs, err)
+		}
+		if labels := resourceLabels(data.Resource); labels != "" {
+			if err := e.writeMetadata("process_labels", pid, 0, labels); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	span := interval{start: data.StartTime, end: data.EndTime}
+	for i, lane := range p.lanes {
+		if fits(lane, span) {
+			p.lanes[i] = append(lane, span)
+			return pid, i + 1, errs
+		}
+	}
+	p.lanes = append(p.lanes, []interval{span})
+	tid = len(p.lanes)
+	if err := e.writeMetadata("thread_name", pid, tid, fmt.Sprintf("lane %d", tid)); err != nil {
+		errs = append(errs, err)
+	}
+	return pid, tid, errs
+}
+
+// prune forgets the intervals that ended more than the retention before
+// the latest end time, and the processes of GroupByTrace without intervals
+// left. It only scans the lanes once per retention period. e.mu must be
+// held.
+func (e *Exporter) prune() {
+	if e.latest.Sub(e.pruned) < e.retention {
+		return
+	}
+	e.pruned = e.latest
+	cutoff := e.latest.Add(-e.retention)
+	for pid, p := range e.procs {
+		empty := true
+		for i, lane := range p.lanes {
+			var kept []interval
+			for _, in := range lane {
+				if in.end.After(cutoff) {
+					kept = append(kept, in)
+				}
+			}
+			p.lanes[i] = kept
+			empty = empty && len(kept) == 0
+		}
+		if empty && e.grouping == GroupByTrace {
+			delete(e.procs, pid)
+			delete(e.pids, p.traceID)
+		}
+	}
+}
+
+// fits reports whether span is disjoint from, encloses or is enclosed by
+// every interval already placed in the lane, so that the viewer can render
+// the lane as a proper stack.
+func fits(lane []interval, span interval) bool {
+	for _, in := range lane {
+		disjoint := !span.end.After(in.start) || !span.start.Before(in.end)
+		nested := (!span.start.Before(in.start) && !span.end.After(in.end)) ||
+			(!in.start.Before(span.start) && !in.end.After(span.end))
+		if !disjoint && !nested {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *Exporter) writeMetadata(name string, pid, tid int, value string) error {
+	arg := "name"
+	if name == "process_labels" {
+		arg = "labels"
+	}
+	return e.write(event{
+		Name: name,
+		Ph:   "M",
+		Pid:  pid,
+		Tid:  tid,
+		Args: map[string]interface{}{arg: value},
+	})
+}
+
+// resourceLabels renders the attributes of res as the comma separated
+// labels of a trace-viewer process.
+func resourceLabels(res *resource.Resource) string {
+	attrs := res.Attributes()
+	labels := make([]string, len(attrs))
+	for i, kv := range attrs {
+		labels[i] = kv.Key.Name + "=" + kv.Value.Emit()
+	}
+	return strings.Join(labels, ",")
+}
+
+// write appends ev to the traceEvents array. e.mu must be held.
+func (e *Exporter) write(ev event) error {
+	b, err := json.Marshal(ev)
+	if err != nil {
+		return fmt.Errorf("error converting trace event to json: %v", err)
+	}
+	var prefix string
+	if e.started {
+		prefix = ",\n"
+	} else {
+		prefix = "{\"traceEvents\":[\n"
+		e.started = true
+	}
+	_, err = e.w.Write(append([]byte(prefix), b...))
+	return err
+}
+
+func attributeArgs(kvs []core.KeyValue) map[string]interface{} {
+	args := make(map[string]interface{}, len(kvs))
+	for _, kv := range kvs {
+		args[kv.Key.Name] = attributeValue(kv.Value)
+	}
+	return args
+}
+
+func attributeValue(v core.Value) interface{} {
+	switch v.Type {
+	case core.BOOL:
+		return v.Bool
+	case core.INT32, core.INT64:
+		return v.Int64
+	case core.UINT32, core.UINT64:
+		return v.Uint64
+	case core.FLOAT32, core.FLOAT64:
+		return v.Float64
+	case core.STRING:
+		return v.String
+	case core.BYTES:
+		return string(v.Bytes)
+	}
+	return nil
+}
+
+// timestamp returns t in microseconds since the Unix epoch.
+func timestamp(t time.Time) float64 {
+	return float64(t.UnixNano()) / 1e3
+}
+
+func micros(d time.Duration) float64 {
+	return float64(d) / 1e3
+}
@@ -0,0 +1,241 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package chrome
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+
+	"go.opentelemetry.io/api/core"
+	"go.opentelemetry.io/api/key"
+	"go.opentelemetry.io/sdk/resource"
+	"go.opentelemetry.io/sdk/trace"
+)
+
+var start = time.Unix(1569931200, 0)
+
+func spanData(traceHigh, spanID, parentID uint64, offset, d time.Duration) *trace.SpanData {
+	return &trace.SpanData{
+		SpanContext: core.SpanContext{
+			TraceID: core.TraceID{High: traceHigh, Low: 1},
+			SpanID:  spanID,
+		},
+		ParentSpanID: parentID,
+		Name:         "span",
+		StartTime:    start.Add(offset),
+		EndTime:      start.Add(offset + d),
+		Status:       codes.OK,
+	}
+}
+
+type document struct {
+	TraceEvents []event `json:"traceEvents"`
+}
+
+func export(t *testing.T, grouping Grouping, spans ...*trace.SpanData) []event {
+	var b bytes.Buffer
+	e, err := NewExporter(Options{Writer: &b, Grouping: grouping})
+	if err != nil {
+		t.Fatalf("Error constructing Chrome trace-event exporter %s", err)
+	}
+	for _, sd := range spans {
+		e.ExportSpan(sd)
+	}
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var doc document
+	if err := json.Unmarshal(b.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid JSON document %q: %v", b.String(), err)
+	}
+	var events []event
+	for _, ev := range doc.TraceEvents {
+		if ev.Ph != "M" {
+			events = append(events, ev)
+		}
+	}
+	return events
+}
+
+func TestNewExporterWithoutWriter(t *testing.T) {
+	if _, err := NewExporter(Options{}); err == nil {
+		t.Errorf("Expected error while creating exporter without writer")
+	}
+}
+
+func TestExporter_Empty(t *testing.T) {
+	if events := export(t, GroupByTrace); len(events) != 0 {
+		t.Errorf("got %d events, want none", len(events))
+	}
+}
+
+func TestExporter_ExportSpan(t *testing.T) {
+	sd := spanData(1, 2, 0, 0, 1500*time.Microsecond)
+	sd.Attributes = []core.KeyValue{key.New("http.method").String("GET")}
+	sd.MessageEvents = []trace.Event{{
+		Message:    "retry",
+		Time:       start.Add(time.Millisecond),
+		Attributes: []core.KeyValue{key.New("attempt").Int(2)},
+	}}
+	events := export(t, GroupByTrace, sd)
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2: %+v", len(events), events)
+	}
+
+	x := events[0]
+	if x.Ph != "X" || x.Name != "span" || x.Pid != 1 || x.Tid != 1 {
+		t.Errorf("unexpected complete event %+v", x)
+	}
+	if got, want := x.Ts, 1569931200e6; got != want {
+		t.Errorf("ts: got %v, want %v", got, want)
+	}
+	if x.Dur == nil || *x.Dur != 1500 {
+		t.Errorf("dur: got %v, want 1500", x.Dur)
+	}
+	if got, want := x.Args["http.method"], "GET"; got != want {
+		t.Errorf("args[http.method]: got %v, want %v", got, want)
+	}
+	if got, want := x.Args["status"], "OK"; got != want {
+		t.Errorf("args[status]: got %v, want %v", got, want)
+	}
+
+	i := events[1]
+	if i.Ph != "i" || i.Name != "retry" || i.Scope != "t" || i.Ts != 1569931200e6+1000 {
+		t.Errorf("unexpected instant event %+v", i)
+	}
+	if got, want := i.Args["attempt"], float64(2); got != want {
+		t.Errorf("args[attempt]: got %v, want %v", got, want)
+	}
+}
+
+func TestExporter_Grouping(t *testing.T) {
+	spans := []*trace.SpanData{
+		// trace 1: child nested in parent, overlapping sibling.
+		spanData(1, 3, 2, time.Millisecond, time.Millisecond),
+		spanData(1, 4, 2, 1500*time.Microsecond, time.Millisecond),
+		spanData(1, 2, 0, 0, 5*time.Millisecond),
+		// trace 2: runs concurrently with trace 1.
+		spanData(2, 5, 0, 500*time.Microsecond, time.Millisecond),
+	}
+	type track struct{ pid, tid int }
+	for _, tt := range []struct {
+		grouping Grouping
+		want     []track
+	}{
+		{GroupByTrace, []track{{1, 1}, {1, 2}, {1, 1}, {2, 1}}},
+		{GroupByLane, []track{{1, 1}, {1, 2}, {1, 1}, {1, 2}}},
+	} {
+		events := export(t, tt.grouping, spans...)
+		for i, ev := range events {
+			if got := (track{ev.Pid, ev.Tid}); got != tt.want[i] {
+				t.Errorf("grouping %d, span %d: got track %v, want %v", tt.grouping, i, got, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestExporter_ProcessLabels(t *testing.T) {
+	sd := spanData(1, 2, 0, 0, time.Millisecond)
+	sd.Resource = resource.New(
+		resource.ServiceName.String("checkout"),
+		resource.HostName.String("host1"),
+	)
+	var b bytes.Buffer
+	e, err := NewExporter(Options{Writer: &b})
+	if err != nil {
+		t.Fatalf("Error constructing Chrome trace-event exporter %s", err)
+	}
+	e.ExportSpan(sd)
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var doc document
+	if err := json.Unmarshal(b.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid JSON document %q: %v", b.String(), err)
+	}
+	var labels interface{}
+	for _, ev := range doc.TraceEvents {
+		if ev.Ph == "M" && ev.Name == "process_labels" {
+			labels = ev.Args["labels"]
+		}
+	}
+	if want := "host.name=host1,service.name=checkout"; labels != want {
+		t.Errorf("process labels: got %v, want %v", labels, want)
+	}
+}
+
+func TestExporter_LaneRetention(t *testing.T) {
+	var b bytes.Buffer
+	e, err := NewExporter(Options{Writer: &b, LaneRetention: time.Second})
+	if err != nil {
+		t.Fatalf("Error constructing Chrome trace-event exporter %s", err)
+	}
+	e.ExportSpan(spanData(1, 2, 0, 0, time.Millisecond))
+	e.ExportSpan(spanData(2, 3, 0, 0, 5*time.Second))
+
+	if len(e.procs) != 1 || len(e.pids) != 1 {
+		t.Fatalf("tracks after retention: got %d processes and %d traces, want 1", len(e.procs), len(e.pids))
+	}
+	if _, ok := e.pids[core.TraceID{High: 2, Low: 1}]; !ok {
+		t.Errorf("trace 2 was forgotten, want trace 1 forgotten")
+	}
+	// A late span of the forgotten trace is shown as a new process.
+	if pid, _, _ := e.track(spanData(1, 4, 0, 0, time.Millisecond)); pid != 3 {
+		t.Errorf("late span of forgotten trace: got pid %d, want 3", pid)
+	}
+}
+
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestExporter_OnError(t *testing.T) {
+	var (
+		e    *Exporter
+		errs []error
+	)
+	e, err := NewExporter(Options{
+		Writer: errWriter{},
+		OnError: func(err error) {
+			errs = append(errs, err)
+			// The hook may use the exporter.
+			if len(errs) == 1 {
+				e.ExportSpan(spanData(1, 3, 0, 0, time.Millisecond))
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("Error constructing Chrome trace-event exporter %s", err)
+	}
+	e.ExportSpan(spanData(1, 2, 0, 0, time.Millisecond))
+
+	// The first span writes the process and thread metadata and the span,
+	// the nested one only the span.
+	if len(errs) != 4 {
+		t.Fatalf("got %d write errors, want 4: %v", len(errs), errs)
+	}
+	for _, err := range errs {
+		if err.Error() != "write failed" {
+			t.Errorf("got error %v, want write failed", err)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package chrome contains an OpenTelemetry tracing exporter that writes
+// spans in the Chrome trace-event format, which can be loaded into
+// chrome://tracing and Perfetto.
+//
+// Spans are written as complete ("X") events and their message events as
+// instant ("i") events. The document is streamed as spans are exported and
+// completed when the exporter is closed.
+package chrome // import "go.opentelemetry.io/exporter/trace/chrome"
@@ -0,0 +1,19 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package file contains an OpenTelemetry tracing exporter that writes
+// spans to size- and age-rotated JSON-lines files, and a reader that
+// parses those files back into SpanData so that they can be replayed into
+// any other exporter.
+package file // import "go.opentelemetry.io/exporter/trace/file"
@@ -0,0 +1,375 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/sdk/trace"
+)
+
+const (
+	defaultMaxSize = 100 * 1024 * 1024
+
+	// backupTimeFormat is used in the names of rotated files. It sorts
+	// lexically in chronological order.
+	backupTimeFormat = "20060102T150405.000000000"
+
+	compressSuffix = ".gz"
+)
+
+// Options are the options to be used when initializing a file exporter.
+type Options struct {
+	// Path is the file spans are written to. Rotated files are kept next
+	// to it, named after it with the rotation time inserted before the
+	// extension, e.g. spans-20191001T120000.000000000.jsonl.
+	Path string
+
+	// MaxSize is the size in bytes after which the file is rotated.
+	// Default is 100 MiB.
+	MaxSize int64
+
+	// MaxAge is the age after which the file is rotated, measured from
+	// the time it was opened. Zero disables age based rotation.
+	MaxAge time.Duration
+
+	// MaxBackups is the number of rotated files to keep. Older files are
+	// removed. Zero keeps all rotated files.
+	MaxBackups int
+
+	// Compress gzips rotated files.
+	Compress bool
+
+	// OnError is the hook to be called when a span cannot be written or a
+	// file cannot be rotated.
+	// If no custom hook is set, errors are logged.
+	// Optional.
+	OnError func(err error)
+}
+
+// Exporter is an implementation of trace.Exporter that writes one JSON
+// object per SpanData to a rotated file.
+type Exporter struct {
+	path       string
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+	compress   bool
+	onError    func(err error)
+	now        func() time.Time
+
+	mu       sync.Mutex // protects the fields below
+	file     *os.File   // nil if the file could not be reopened
+	size     int64
+	openedAt time.Time
+	closed   bool
+
+	millMu sync.Mutex // serializes compression and removal of rotated files
+	millWg sync.WaitGroup
+}
+
+var _ trace.Exporter = (*Exporter)(nil)
+
+// NewExporter returns a trace.Exporter implementation that writes the
+// collected spans to o.Path. If the file already exists, spans are
+// appended to it.
+func NewExporter(o Options) (*Exporter, error) {
+	if o.Path == "" {
+		return nil, errors.New("missing path for file exporter")
+	}
+	if o.MaxSize < 0 || o.MaxAge < 0 || o.MaxBackups < 0 {
+		return nil, errors.New("negative rotation limit for file exporter")
+	}
+	maxSize := o.MaxSize
+	if maxSize == 0 {
+		maxSize = defaultMaxSize
+	}
+	onError := o.OnError
+	if onError == nil {
+		onError = func(err error) {
+			log.Printf("Error when writing spans to file: %v", err)
+		}
+	}
+	e := &Exporter{
+		path:       o.Path,
+		maxSize:    maxSize,
+		maxAge:     o.MaxAge,
+		maxBackups: o.MaxBackups,
+		compress:   o.Compress,
+		onError:    onError,
+		now:        time.Now,
+	}
+	if err := e.open(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// ExportSpan writes a SpanData as a single line of JSON. It rotates the
+// file beforehand if the line would exceed the size limit or if the file
+// is older than the age limit. If the rotation fails, the line is written
+// to the file that is open.
+func (e *Exporter) ExportSpan(data *trace.SpanData) {
+	line, err := json.Marshal(data)
+	if err != nil {
+		e.onError(fmt.Errorf("error converting spanData to json: %v", err))
+		return
+	}
+	line = append(line, '\n')
+
+	e.mu.Lock()
+	errs := e.write(line)
+	e.mu.Unlock()
+	for _, err := range errs {
+		e.onError(err)
+	}
+}
+
+// write writes line to the file, rotating it if needed, and returns the
+// errors that occurred. e.mu must be held.
+func (e *Exporter) write(line []byte) []error {
+	if e.closed {
+		return nil
+	}
+	var errs []error
+	if e.shouldRotate(int64(len(line))) {
+		if err := e.rotate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if e.file == nil {
+		if err := e.open(); err != nil {
+			return append(errs, err)
+		}
+	}
+	n, err := e.file.Write(line)
+	e.size += int64(n)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// Rotate closes the current file, renames it and opens a new one.
+func (e *Exporter) Rotate() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return errors.New("file exporter is closed")
+	}
+	return e.rotate()
+}
+
+// Close closes the current file and waits for pending compression of
+// rotated files. Spans exported after Close are discarded.
+func (e *Exporter) Close() error {
+	e.mu.Lock()
+	if e.closed {
+		e.mu.Unlock()
+		return nil
+	}
+	e.closed = true
+	var err error
+	if e.file != nil {
+		err = e.file.Close()
+	}
+	e.mu.Unlock()
+	// The OnError hook of a pending mill may use the exporter.
+	e.millWg.Wait()
+	return err
+}
+
+func (e *Exporter) shouldRotate(n int64) bool {
+	if e.size > 0 && e.size+n > e.maxSize {
+		return true
+	}
+	return e.maxAge > 0 && e.now().Sub(e.openedAt) >= e.maxAge
+}
+
+// open opens or creates the file at e.path. e.mu must be held.
+func (e *Exporter) open() error {
+	if err := os.MkdirAll(filepath.Dir(e.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(e.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	e.file = f
+	e.size = info.Size()
+	e.openedAt = e.now()
+	return nil
+}
+
+// rotate renames the current file to a backup name and opens a new file.
+// If the file cannot be renamed, it is reopened to append to it. If no file
+// can be opened, e.file is left nil. e.mu must be held.
+func (e *Exporter) rotate() error {
+	if e.file != nil {
+		// The file is renamed even if closing it failed, as it cannot be
+		// written anymore either way.
+		err := e.file.Close()
+		e.file = nil
+		if err != nil {
+			if oerr := e.open(); oerr != nil {
+				return oerr
+			}
+			return err
+		}
+	}
+	backup := e.backupName(e.now())
+	if err := os.Rename(e.path, backup); err != nil {
+		_ = e.open()
+		return err
+	}
+	if err := e.open(); err != nil {
+		return err
+	}
+	e.millWg.Add(1)
+	go e.mill()
+	return nil
+}
+
+// mill compresses the rotated files if needed and removes the rotated
+// files exceeding the configured number of backups. Rotated files are
+// processed as a whole, so the order in which concurrent mill calls run
+// does not matter.
+func (e *Exporter) mill() {
+	defer e.millWg.Done()
+	e.millMu.Lock()
+	errs := e.millLocked()
+	e.millMu.Unlock()
+	for _, err := range errs {
+		e.onError(err)
+	}
+}
+
+// millLocked does the work of mill and returns the errors that occurred.
+// e.millMu must be held.
+func (e *Exporter) millLocked() []error {
+	backups, err := e.backups()
+	if err != nil {
+		return []error{err}
+	}
+	var errs []error
+	if e.maxBackups > 0 {
+		for len(backups) > e.maxBackups {
+			if err := os.Remove(backups[0]); err != nil {
+				errs = append(errs, err)
+			}
+			backups = backups[1:]
+		}
+	}
+	if !e.compress {
+		return errs
+	}
+	for _, backup := range backups {
+		if strings.HasSuffix(backup, compressSuffix) {
+			continue
+		}
+		if err := compressFile(backup); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+func (e *Exporter) backupName(t time.Time) string {
+	dir, prefix, ext := e.nameParts()
+	return filepath.Join(dir, prefix+t.UTC().Format(backupTimeFormat)+ext)
+}
+
+// nameParts splits e.path into the directory, the prefix of rotated file
+// names and the extension.
+func (e *Exporter) nameParts() (dir, prefix, ext string) {
+	dir = filepath.Dir(e.path)
+	base := filepath.Base(e.path)
+	ext = filepath.Ext(base)
+	return dir, strings.TrimSuffix(base, ext) + "-", ext
+}
+
+// backups returns the names of the rotated files, oldest first.
+func (e *Exporter) backups() ([]string, error) {
+	dir, prefix, ext := e.nameParts()
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		ts := strings.TrimSuffix(strings.TrimSuffix(name, compressSuffix), ext)
+		ts = strings.TrimPrefix(ts, prefix)
+		if _, err := time.Parse(backupTimeFormat, ts); err != nil {
+			continue
+		}
+		names = append(names, filepath.Join(dir, name))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// compressFile gzips name into name.gz and removes name.
+func compressFile(name string) (err error) {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(name+compressSuffix, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			dst.Close()
+			os.Remove(name + compressSuffix)
+		}
+	}()
+
+	gz := gzip.NewWriter(dst)
+	if _, err = io.Copy(gz, src); err != nil {
+		return err
+	}
+	if err = gz.Close(); err != nil {
+		return err
+	}
+	if err = dst.Close(); err != nil {
+		return err
+	}
+	src.Close()
+	return os.Remove(name)
+}
@@ -0,0 +1,283 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+
+	"go.opentelemetry.io/api/core"
+	"go.opentelemetry.io/api/key"
+	"go.opentelemetry.io/sdk/trace"
+)
+
+func testSpanData(spanID uint64) *trace.SpanData {
+	now := time.Date(2019, time.October, 1, 12, 0, 0, 0, time.UTC)
+	return &trace.SpanData{
+		SpanContext: core.SpanContext{
+			TraceID:    core.TraceID{High: 0x0102030405060708, Low: 0x090a0b0c0d0e0f10},
+			SpanID:     spanID,
+			TraceFlags: core.TraceFlagsSampled,
+		},
+		ParentSpanID: 0x0a0b0c0d0e0f1011,
+		Name:         "/foo",
+		StartTime:    now,
+		EndTime:      now.Add(time.Millisecond),
+		Attributes: []core.KeyValue{
+			key.New("string").String("value"),
+			key.New("int").Int64(42),
+		},
+		MessageEvents: []trace.Event{{
+			Message:    "event",
+			Time:       now,
+			Attributes: []core.KeyValue{key.New("bool").Bool(true)},
+		}},
+		Status: codes.NotFound,
+	}
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "file-exporter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func newTestExporter(t *testing.T, o Options) *Exporter {
+	o.OnError = func(err error) { t.Errorf("unexpected export error: %v", err) }
+	e, err := NewExporter(o)
+	if err != nil {
+		t.Fatalf("Error constructing file exporter %s", err)
+	}
+	return e
+}
+
+func countSpans(t *testing.T, paths ...string) int {
+	var n int
+	for _, path := range paths {
+		r, err := Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for {
+			if _, err := r.Read(); err != nil {
+				break
+			}
+			n++
+		}
+		r.Close()
+	}
+	return n
+}
+
+func TestNewExporterWithoutPath(t *testing.T) {
+	if _, err := NewExporter(Options{}); err == nil {
+		t.Errorf("Expected error while creating exporter without path")
+	}
+}
+
+func TestExporter_RotateBySize(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "spans.jsonl")
+
+	e := newTestExporter(t, Options{Path: path, MaxSize: 1})
+	for i := 0; i < 3; i++ {
+		e.ExportSpan(testSpanData(uint64(i + 1)))
+	}
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := Files(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(files), 3; got != want {
+		t.Fatalf("number of files: got %d, want %d (%v)", got, want, files)
+	}
+	if files[2] != path {
+		t.Errorf("last file: got %s, want %s", files[2], path)
+	}
+	for _, f := range files {
+		if got := countSpans(t, f); got != 1 {
+			t.Errorf("%s: got %d spans, want 1", f, got)
+		}
+	}
+}
+
+func TestExporter_RotateFails(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "spans.jsonl")
+
+	var (
+		e    *Exporter
+		errs int
+	)
+	e, err := NewExporter(Options{
+		Path:    path,
+		MaxSize: 1,
+		OnError: func(err error) {
+			errs++
+			// The hook may use the exporter.
+			if errs == 1 && e.Rotate() == nil {
+				t.Error("Rotate onto a directory succeeded")
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("Error constructing file exporter %s", err)
+	}
+	now := time.Date(2019, time.October, 1, 12, 0, 0, 0, time.UTC)
+	e.now = func() time.Time { return now }
+	// A non-empty directory in place of the rotated file makes the rename
+	// fail.
+	backup := e.backupName(now)
+	if err := os.MkdirAll(filepath.Join(backup, "blocker"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		e.ExportSpan(testSpanData(uint64(i + 1)))
+	}
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if errs != 2 {
+		t.Errorf("OnError: got %d calls, want 2", errs)
+	}
+	if got := countSpans(t, path); got != 3 {
+		t.Errorf("got %d spans, want 3", got)
+	}
+}
+
+func TestExporter_RotateByAge(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "spans.jsonl")
+
+	now := time.Date(2019, time.October, 1, 12, 0, 0, 0, time.UTC)
+	e := newTestExporter(t, Options{Path: path, MaxAge: time.Hour})
+	e.now = func() time.Time { return now }
+	e.openedAt = now
+
+	e.ExportSpan(testSpanData(1))
+	now = now.Add(30 * time.Minute)
+	e.ExportSpan(testSpanData(2))
+	now = now.Add(30 * time.Minute)
+	e.ExportSpan(testSpanData(3))
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	backup := filepath.Join(dir, "spans-20191001T130000.000000000.jsonl")
+	if got, want := countSpans(t, backup), 2; got != want {
+		t.Errorf("rotated file: got %d spans, want %d", got, want)
+	}
+	if got, want := countSpans(t, path), 1; got != want {
+		t.Errorf("current file: got %d spans, want %d", got, want)
+	}
+}
+
+func TestExporter_MaxBackupsAndCompress(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "spans.jsonl")
+
+	now := time.Date(2019, time.October, 1, 12, 0, 0, 0, time.UTC)
+	e := newTestExporter(t, Options{Path: path, MaxSize: 1, MaxBackups: 2, Compress: true})
+	e.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+	for i := 0; i < 5; i++ {
+		e.ExportSpan(testSpanData(uint64(i + 1)))
+	}
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := Files(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(files), 3; got != want {
+		t.Fatalf("number of files: got %d, want %d (%v)", got, want, files)
+	}
+	for _, f := range files[:2] {
+		if !strings.HasSuffix(f, ".jsonl.gz") {
+			t.Errorf("rotated file %s is not compressed", f)
+		}
+	}
+	if got, want := countSpans(t, files...), 3; got != want {
+		t.Errorf("got %d spans, want %d", got, want)
+	}
+}
+
+func TestExporter_Concurrent(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "spans.jsonl")
+
+	e := newTestExporter(t, Options{Path: path, MaxSize: 4096})
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				e.ExportSpan(testSpanData(uint64(i + 1)))
+			}
+		}()
+	}
+	wg.Wait()
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := Files(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := countSpans(t, files...), 400; got != want {
+		t.Errorf("got %d spans, want %d", got, want)
+	}
+}
+
+func TestExporter_Append(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "spans.jsonl")
+
+	for i := 0; i < 2; i++ {
+		e := newTestExporter(t, Options{Path: path})
+		e.ExportSpan(testSpanData(1))
+		if err := e.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got, want := countSpans(t, path), 2; got != want {
+		t.Errorf("got %d spans, want %d", got, want)
+	}
+}
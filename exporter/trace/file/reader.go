@@ -0,0 +1,129 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/sdk/trace"
+)
+
+// Reader parses spans written by Exporter.
+type Reader struct {
+	dec     *json.Decoder
+	closers []io.Closer
+	n       int
+}
+
+// NewReader returns a Reader that parses JSON-lines spans from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{dec: json.NewDecoder(r)}
+}
+
+// Open returns a Reader for the file at path. Files ending in .gz are
+// decompressed transparently. The Reader must be closed after use.
+func Open(path string) (*Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(path, compressSuffix) {
+		r := NewReader(f)
+		r.closers = []io.Closer{f}
+		return r, nil
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r := NewReader(gz)
+	r.closers = []io.Closer{gz, f}
+	return r, nil
+}
+
+// Read returns the next span. It returns io.EOF when there are no more
+// spans.
+func (r *Reader) Read() (*trace.SpanData, error) {
+	var sd trace.SpanData
+	if err := r.dec.Decode(&sd); err != nil {
+		if err == io.EOF {
+			return nil, err
+		}
+		return nil, fmt.Errorf("error parsing span %d: %v", r.n+1, err)
+	}
+	r.n++
+	return &sd, nil
+}
+
+// Close closes the underlying file, if the Reader was created by Open.
+func (r *Reader) Close() error {
+	var err error
+	for _, c := range r.closers {
+		if cerr := c.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// Replay reads all spans from the files at paths, in order, and passes
+// them to e. It stops at the first error.
+func Replay(e trace.Exporter, paths ...string) error {
+	for _, path := range paths {
+		if err := replayFile(e, path); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	return nil
+}
+
+func replayFile(e trace.Exporter, path string) error {
+	r, err := Open(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	for {
+		sd, err := r.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		e.ExportSpan(sd)
+	}
+}
+
+// Files returns the rotated files of the exporter writing to path, oldest
+// first, followed by path itself if it exists. The result can be passed
+// to Replay.
+func Files(path string) ([]string, error) {
+	e := &Exporter{path: path}
+	files, err := e.backups()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); err == nil {
+		files = append(files, path)
+	}
+	return files, nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package file
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"go.opentelemetry.io/sdk/trace"
+)
+
+type testExporter struct {
+	spans []*trace.SpanData
+}
+
+func (t *testExporter) ExportSpan(s *trace.SpanData) {
+	t.spans = append(t.spans, s)
+}
+
+func TestReplay(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "spans.jsonl")
+
+	want := []*trace.SpanData{testSpanData(1), testSpanData(2), testSpanData(3)}
+	e := newTestExporter(t, Options{Path: path, MaxSize: 1, Compress: true})
+	for _, sd := range want {
+		e.ExportSpan(sd)
+	}
+	if err := e.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := Files(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var te testExporter
+	if err := Replay(&te, files...); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(te.spans, want); diff != "" {
+		t.Errorf("Replay: -got +want %s", diff)
+	}
+}
+
+func TestReader_Truncated(t *testing.T) {
+	r := NewReader(strings.NewReader(`{"Name":"ok"}` + "\n" + `{"Name":"trunc`))
+	sd, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sd.Name != "ok" {
+		t.Errorf("span name: got %q, want %q", sd.Name, "ok")
+	}
+	if _, err := r.Read(); err == nil || err == io.EOF {
+		t.Errorf("expected parse error for truncated span, got %v", err)
+	}
+}
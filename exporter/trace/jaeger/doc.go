@@ -12,5 +12,6 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-// Package jaeger contains an OpenTelemetry tracing exporter for Jaeger.
+// Package jaeger contains an OpenTelemetry tracing exporter for Jaeger and a
+// sampler applying the sampling strategies managed by Jaeger.
 package jaeger // import "go.opentelemetry.io/exporter/trace/jaeger"
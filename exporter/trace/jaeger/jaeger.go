@@ -16,12 +16,15 @@ package jaeger
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"strings"
+	"sync"
 
 	"github.com/apache/thrift/lib/go/thrift"
 	"google.golang.org/api/support/bundler"
@@ -29,6 +32,7 @@ import (
 
 	"go.opentelemetry.io/api/core"
 	gen "go.opentelemetry.io/exporter/trace/jaeger/internal/gen-go/jaeger"
+	"go.opentelemetry.io/sdk/resource"
 	"go.opentelemetry.io/sdk/trace"
 )
 
@@ -90,27 +94,16 @@ func NewExporter(o Options) (*Exporter, error) {
 		}
 		log.Printf("Error when uploading spans to Jaeger: %v", err)
 	}
-	service := o.Process.ServiceName
-	if service == "" {
-		service = defaultServiceName
-	}
-	tags := make([]*gen.Tag, len(o.Process.Tags))
-	for i, tag := range o.Process.Tags {
-		tags[i] = attributeToTag(tag.key, tag.value)
-	}
 	e := &Exporter{
 		endpoint:      endpoint,
 		agentEndpoint: o.AgentEndpoint,
 		client:        client,
 		username:      o.Username,
 		password:      o.Password,
-		process: &gen.Process{
-			ServiceName: service,
-			Tags:        tags,
-		},
+		process:       o.Process,
 	}
-	bundler := bundler.NewBundler((*gen.Span)(nil), func(bundle interface{}) {
-		if err := e.upload(bundle.([]*gen.Span)); err != nil {
+	bundler := bundler.NewBundler((*span)(nil), func(bundle interface{}) {
+		if err := e.upload(bundle.([]*span)); err != nil {
 			onError(err)
 		}
 	})
@@ -127,9 +120,11 @@ func NewExporter(o Options) (*Exporter, error) {
 }
 
 // Process contains the information exported to jaeger about the source
-// of the trace data.
+// of the trace data. It is combined with the resource of every exported
+// span.
 type Process struct {
-	// ServiceName is the Jaeger service name.
+	// ServiceName is the Jaeger service name. If empty, the value of the
+	// service.name attribute of the span resource is used.
 	ServiceName string
 
 	// Tags are added to Jaeger Process exports
@@ -147,18 +142,29 @@ type Tag struct {
 type Exporter struct {
 	endpoint      string
 	agentEndpoint string
-	process       *gen.Process
+	process       Process
 	bundler       *bundler.Bundler
 	client        *agentClientUDP
 
 	username, password string
+
+	// flushing is closed when the running flush of the bundler completes.
+	// It is nil if no flush is running.
+	flushMu  sync.Mutex
+	flushing chan struct{}
 }
 
 var _ trace.Exporter = (*Exporter)(nil)
 
+// span is a bundled span together with the resource it was produced by.
+type span struct {
+	span     *gen.Span
+	resource *resource.Resource
+}
+
 // ExportSpan exports a SpanData to Jaeger.
 func (e *Exporter) ExportSpan(data *trace.SpanData) {
-	_ = e.bundler.Add(spanDataToThrift(data), 1)
+	_ = e.bundler.Add(&span{span: spanDataToThrift(data), resource: data.Resource}, 1)
 	// TODO(jbd): Handle oversized bundlers.
 }
 
@@ -323,15 +329,121 @@ func (e *Exporter) Flush() {
 	e.bundler.Flush()
 }
 
-func (e *Exporter) upload(spans []*gen.Span) error {
-	batch := &gen.Batch{
-		Spans:   spans,
-		Process: e.process,
+var _ trace.Flusher = (*Exporter)(nil)
+
+// ForceFlush is like Flush, but returns the error of the context if the
+// context is done before the spans were uploaded. Upload errors are
+// reported to Options.OnError.
+//
+// If the context is done first, the flush continues in the background.
+// Calls made while it is running wait for it rather than starting another
+// one, and then flush the spans exported since it started.
+func (e *Exporter) ForceFlush(ctx context.Context) error {
+	e.flushMu.Lock()
+	stale := e.flushing
+	e.flushMu.Unlock()
+	for {
+		done := e.startFlush()
+		select {
+		case <-done:
+			if done != stale {
+				return nil
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// startFlush flushes the bundler in the background unless a flush is
+// running already, and returns the channel of the running flush.
+func (e *Exporter) startFlush() chan struct{} {
+	e.flushMu.Lock()
+	defer e.flushMu.Unlock()
+	if e.flushing == nil {
+		done := make(chan struct{})
+		e.flushing = done
+		go func() {
+			e.bundler.Flush()
+			e.flushMu.Lock()
+			e.flushing = nil
+			e.flushMu.Unlock()
+			close(done)
+		}()
+	}
+	return e.flushing
+}
+
+// upload sends the spans in one batch per distinct resource, as Jaeger
+// attaches the process information to a batch rather than to a span.
+// A failed batch does not keep the remaining batches from being sent; the
+// first error is returned after all batches were tried.
+func (e *Exporter) upload(spans []*span) error {
+	var batches []*gen.Batch
+	index := make(map[string]*gen.Batch)
+	for _, s := range spans {
+		key := resourceKey(s.resource)
+		batch, ok := index[key]
+		if !ok {
+			batch = &gen.Batch{Process: e.jaegerProcess(s.resource)}
+			index[key] = batch
+			batches = append(batches, batch)
+		}
+		batch.Spans = append(batch.Spans, s.span)
 	}
-	if e.endpoint != "" {
-		return e.uploadCollector(batch)
+	var firstErr error
+	for _, batch := range batches {
+		var err error
+		if e.endpoint != "" {
+			err = e.uploadCollector(batch)
+		} else {
+			err = e.uploadAgent(batch)
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// jaegerProcess returns the Jaeger process for spans of the given resource.
+// The service name of the options takes precedence over the service.name
+// attribute of the resource. The remaining resource attributes are appended
+// to the tags of the options.
+func (e *Exporter) jaegerProcess(res *resource.Resource) *gen.Process {
+	service := e.process.ServiceName
+	if v, ok := res.Value(resource.ServiceName); ok && service == "" {
+		service = v.Emit()
+	}
+	if service == "" {
+		service = defaultServiceName
+	}
+	tags := make([]*gen.Tag, 0, len(e.process.Tags)+res.Len())
+	for _, tag := range e.process.Tags {
+		tags = append(tags, attributeToTag(tag.key, tag.value))
+	}
+	for _, kv := range res.Attributes() {
+		if kv.Key == resource.ServiceName {
+			continue
+		}
+		if tag := coreAttributeToTag(kv); tag != nil {
+			tags = append(tags, tag)
+		}
+	}
+	return &gen.Process{
+		ServiceName: service,
+		Tags:        tags,
+	}
+}
+
+// resourceKey returns a string identifying the attributes of res.
+func resourceKey(res *resource.Resource) string {
+	attrs := res.Attributes()
+	parts := make([]string, len(attrs))
+	for i, kv := range attrs {
+		parts[i] = fmt.Sprintf("%q=%d:%q", kv.Key.Name, kv.Value.Type, kv.Value.Emit())
 	}
-	return e.uploadAgent(batch)
+	return strings.Join(parts, ",")
 }
 
 func (e *Exporter) uploadAgent(batch *gen.Batch) error {
@@ -15,15 +15,22 @@
 package jaeger
 
 import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"sort"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/apache/thrift/lib/go/thrift"
 	"github.com/google/go-cmp/cmp"
 	"google.golang.org/grpc/codes"
 
 	"go.opentelemetry.io/api/core"
 	gen "go.opentelemetry.io/exporter/trace/jaeger/internal/gen-go/jaeger"
+	"go.opentelemetry.io/sdk/resource"
 	"go.opentelemetry.io/sdk/trace"
 )
 
@@ -101,3 +108,157 @@ func Test_spanDataToThrift(t *testing.T) {
 		})
 	}
 }
+
+func TestExporterProcessFromResource(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		batches []*gen.Batch
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("reading request: %v", err)
+			return
+		}
+		buf := thrift.NewTMemoryBuffer()
+		buf.Write(body)
+		batch := gen.NewBatch()
+		if err := batch.Read(thrift.NewTBinaryProtocolTransport(buf)); err != nil {
+			t.Errorf("decoding batch: %v", err)
+			return
+		}
+		mu.Lock()
+		batches = append(batches, batch)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	e, err := NewExporter(Options{CollectorEndpoint: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkout := resource.New(
+		resource.ServiceName.String("checkout"),
+		resource.HostName.String("host1"),
+	)
+	for _, res := range []*resource.Resource{
+		checkout,
+		nil,
+		resource.New(resource.HostName.String("host1"), resource.ServiceName.String("checkout")),
+	} {
+		e.ExportSpan(&trace.SpanData{Name: "span", Resource: res})
+	}
+	e.Flush()
+
+	mu.Lock()
+	defer mu.Unlock()
+	type process struct {
+		Service string
+		Tags    []string
+		Spans   int
+	}
+	var got []process
+	for _, b := range batches {
+		p := process{Service: b.Process.ServiceName, Spans: len(b.Spans)}
+		for _, tag := range b.Process.Tags {
+			p.Tags = append(p.Tags, tag.Key+"="+tag.GetVStr())
+		}
+		got = append(got, p)
+	}
+	want := []process{
+		{Service: "checkout", Tags: []string{"host.name=host1"}, Spans: 2},
+		{Service: defaultServiceName, Spans: 1},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Errorf("batches: -got +want %s", diff)
+	}
+}
+
+func TestExporterUploadContinuesAfterError(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests int
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	e, err := NewExporter(Options{CollectorEndpoint: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = e.upload([]*span{
+		{span: &gen.Span{OperationName: "a"}, resource: resource.New(resource.ServiceName.String("a"))},
+		{span: &gen.Span{OperationName: "b"}, resource: resource.New(resource.ServiceName.String("b"))},
+	})
+	if err == nil {
+		t.Error("upload: got no error, want the error of the first batch")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if requests != 2 {
+		t.Errorf("requests: got %d, want 2", requests)
+	}
+}
+
+func TestExporterProcessServiceNameOverride(t *testing.T) {
+	e := &Exporter{process: Process{ServiceName: "override"}}
+	got := e.jaegerProcess(resource.New(resource.ServiceName.String("checkout")))
+	if got.ServiceName != "override" {
+		t.Errorf("service name: got %q, want %q", got.ServiceName, "override")
+	}
+	if len(got.Tags) != 0 {
+		t.Errorf("tags: got %v, want none", got.Tags)
+	}
+}
+
+func TestExporterForceFlush(t *testing.T) {
+	uploaded := make(chan struct{}, 1)
+	block := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+		uploaded <- struct{}{}
+	}))
+	defer srv.Close()
+
+	e, err := NewExporter(Options{CollectorEndpoint: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.ExportSpan(&trace.SpanData{Name: "span"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := e.ForceFlush(ctx); err != context.DeadlineExceeded {
+		t.Errorf("ForceFlush with blocked collector: got %v, want %v", err, context.DeadlineExceeded)
+	}
+	e.flushMu.Lock()
+	running := e.flushing
+	e.flushMu.Unlock()
+	for i := 0; i < 3; i++ {
+		if err := e.ForceFlush(ctx); err != context.DeadlineExceeded {
+			t.Errorf("ForceFlush with blocked collector: got %v, want %v", err, context.DeadlineExceeded)
+		}
+	}
+	e.flushMu.Lock()
+	if e.flushing != running {
+		t.Error("ForceFlush started another flush while one was running")
+	}
+	e.flushMu.Unlock()
+
+	close(block)
+	if err := e.ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-uploaded:
+	default:
+		t.Error("span not uploaded after ForceFlush")
+	}
+}
@@ -0,0 +1,401 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jaeger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/api/core"
+	apitrace "go.opentelemetry.io/api/trace"
+	"go.opentelemetry.io/sdk/trace"
+)
+
+const (
+	defaultRefreshInterval     = time.Minute
+	defaultSamplingProbability = 0.001
+	defaultFetchTimeout        = 10 * time.Second
+	defaultInitialFetchTimeout = time.Second
+)
+
+// RemoteSamplerOptions are the options to be used when initializing a
+// RemoteSampler.
+type RemoteSamplerOptions struct {
+	// Endpoint is the URL of the sampling strategies endpoint of the
+	// Jaeger agent, for example http://localhost:5778/sampling.
+	// Required.
+	Endpoint string
+
+	// ServiceName is the service whose strategy is requested.
+	// Required.
+	ServiceName string
+
+	// RefreshInterval is the interval in which the strategy is
+	// refreshed.
+	// Default is one minute.
+	RefreshInterval time.Duration
+
+	// DefaultSampler is used until a strategy was fetched successfully.
+	// Default is a probability sampler sampling 0.1% of the traces.
+	DefaultSampler trace.Sampler
+
+	// HTTPClient is the client used to fetch strategies.
+	// Default is a client with a timeout of 10 seconds.
+	HTTPClient *http.Client
+
+	// InitialFetchTimeout is the time NewRemoteSampler waits for the
+	// first strategy. If it is exceeded, DefaultSampler is used until the
+	// strategy is refreshed.
+	// Default is one second.
+	InitialFetchTimeout time.Duration
+
+	// OnError is the hook to be called when a strategy cannot be fetched
+	// or parsed. The current strategy stays in effect.
+	// If no custom hook is set, errors are logged.
+	// Optional.
+	OnError func(err error)
+}
+
+// RemoteSampler samples spans according to the sampling strategy that the
+// Jaeger agent serves for a service. It supports probabilistic,
+// rate-limiting and per-operation strategies, where the span name is used
+// as operation name. It implements apitrace.Sampler and is usually wrapped
+// with trace.ParentBased:
+//
+//	rs, err := jaeger.NewRemoteSampler(jaeger.RemoteSamplerOptions{...})
+//	...
+//	trace.ApplyConfig(trace.Config{DefaultSampler: trace.ParentBased(rs)})
+type RemoteSampler struct {
+	url     string
+	client  *http.Client
+	onError func(err error)
+
+	sampler atomic.Value // holds samplerHolder
+
+	mu       sync.Mutex // serializes updates
+	strategy *strategyResponse
+
+	// ctx is canceled by Close to abort requests in flight.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	stopOnce sync.Once
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+}
+
+// NewRemoteSampler returns a RemoteSampler. It waits up to
+// o.InitialFetchTimeout for the strategy and then refreshes it in the
+// background until Close is called. If the first fetch fails or times out,
+// o.DefaultSampler is used until a later fetch succeeds.
+func NewRemoteSampler(o RemoteSamplerOptions) (*RemoteSampler, error) {
+	if o.Endpoint == "" {
+		return nil, errors.New("missing endpoint for Jaeger remote sampler")
+	}
+	if o.ServiceName == "" {
+		return nil, errors.New("missing service name for Jaeger remote sampler")
+	}
+	u, err := url.Parse(o.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	q.Set("service", o.ServiceName)
+	u.RawQuery = q.Encode()
+
+	interval := o.RefreshInterval
+	if interval <= 0 {
+		interval = defaultRefreshInterval
+	}
+	defaultSampler := o.DefaultSampler
+	if defaultSampler == nil {
+		defaultSampler = trace.ProbabilitySampler(defaultSamplingProbability)
+	}
+	client := o.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: defaultFetchTimeout}
+	}
+	initialTimeout := o.InitialFetchTimeout
+	if initialTimeout <= 0 {
+		initialTimeout = defaultInitialFetchTimeout
+	}
+	onError := o.OnError
+	if onError == nil {
+		onError = func(err error) {
+			log.Printf("Error when fetching Jaeger sampling strategy: %v", err)
+		}
+	}
+	s := &RemoteSampler{
+		url:     u.String(),
+		client:  client,
+		onError: onError,
+		stopCh:  make(chan struct{}),
+		doneCh:  make(chan struct{}),
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.sampler.Store(samplerHolder{defaultSampler})
+	ctx, cancel := context.WithTimeout(s.ctx, initialTimeout)
+	defer cancel()
+	if err := s.update(ctx); err != nil {
+		onError(err)
+	}
+	go s.run(interval)
+	return s, nil
+}
+
+var _ apitrace.Sampler = (*RemoteSampler)(nil)
+
+// ShouldSample implements apitrace.Sampler using the current strategy.
+func (s *RemoteSampler) ShouldSample(p trace.SamplingParameters) trace.SamplingDecision {
+	return s.sampler.Load().(samplerHolder).ShouldSample(p)
+}
+
+// Description implements apitrace.Sampler.
+func (s *RemoteSampler) Description() string {
+	return "JaegerRemoteSampler{" + s.url + "}"
+}
+
+// Update fetches the strategy and applies it if it changed. Samplers of
+// an unchanged strategy are kept, so that rate limits are not reset.
+// A request in flight is aborted by Close.
+func (s *RemoteSampler) Update() error {
+	return s.update(s.ctx)
+}
+
+func (s *RemoteSampler) update(ctx context.Context) error {
+	strategy, err := s.fetch(ctx)
+	if err != nil {
+		return err
+	}
+	sampler, err := strategy.sampler()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if reflect.DeepEqual(strategy, s.strategy) {
+		return nil
+	}
+	s.strategy = strategy
+	s.sampler.Store(samplerHolder{sampler})
+	return nil
+}
+
+// Close stops refreshing the strategy and aborts a fetch in flight. The
+// current strategy stays in effect.
+func (s *RemoteSampler) Close() {
+	s.stopOnce.Do(func() {
+		s.cancel()
+		close(s.stopCh)
+		<-s.doneCh
+	})
+}
+
+func (s *RemoteSampler) run(interval time.Duration) {
+	defer close(s.doneCh)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stopCh:
+			return
+		case <-ticker.C:
+			if err := s.Update(); err != nil {
+				s.onError(err)
+			}
+		}
+	}
+}
+
+func (s *RemoteSampler) fetch(ctx context.Context) (*strategyResponse, error) {
+	req, err := http.NewRequest(http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("failed to fetch sampling strategy; HTTP status code: %d", resp.StatusCode)
+	}
+	strategy := &strategyResponse{}
+	if err := json.Unmarshal(body, strategy); err != nil {
+		return nil, fmt.Errorf("error parsing sampling strategy: %v", err)
+	}
+	return strategy, nil
+}
+
+// samplerHolder stores samplers of different types in an atomic.Value.
+type samplerHolder struct {
+	trace.Sampler
+}
+
+// strategyResponse is the JSON representation of a sampling strategy
+// served by the Jaeger agent.
+type strategyResponse struct {
+	StrategyType          strategyType                   `json:"strategyType"`
+	ProbabilisticSampling *probabilisticSamplingStrategy `json:"probabilisticSampling"`
+	RateLimitingSampling  *rateLimitingSamplingStrategy  `json:"rateLimitingSampling"`
+	OperationSampling     *perOperationSamplingStrategy  `json:"operationSampling"`
+}
+
+type probabilisticSamplingStrategy struct {
+	SamplingRate float64 `json:"samplingRate"`
+}
+
+type rateLimitingSamplingStrategy struct {
+	MaxTracesPerSecond float64 `json:"maxTracesPerSecond"`
+}
+
+type perOperationSamplingStrategy struct {
+	DefaultSamplingProbability       float64             `json:"defaultSamplingProbability"`
+	DefaultLowerBoundTracesPerSecond float64             `json:"defaultLowerBoundTracesPerSecond"`
+	PerOperationStrategies           []operationStrategy `json:"perOperationStrategies"`
+}
+
+type operationStrategy struct {
+	Operation             string                         `json:"operation"`
+	ProbabilisticSampling *probabilisticSamplingStrategy `json:"probabilisticSampling"`
+}
+
+// strategyType is PROBABILISTIC or RATE_LIMITING. Agents encode it either
+// as name or as number.
+type strategyType string
+
+const (
+	strategyProbabilistic strategyType = "PROBABILISTIC"
+	strategyRateLimiting  strategyType = "RATE_LIMITING"
+)
+
+func (t *strategyType) UnmarshalJSON(b []byte) error {
+	var n int
+	if err := json.Unmarshal(b, &n); err == nil {
+		switch n {
+		case 0:
+			*t = strategyProbabilistic
+		case 1:
+			*t = strategyRateLimiting
+		default:
+			return fmt.Errorf("unknown strategy type %d", n)
+		}
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	*t = strategyType(s)
+	return nil
+}
+
+// sampler returns the sampler implementing the strategy. Per-operation
+// strategies take precedence over the strategy type.
+func (r *strategyResponse) sampler() (trace.Sampler, error) {
+	if ops := r.OperationSampling; ops != nil {
+		operations := make(map[string]trace.Sampler, len(ops.PerOperationStrategies))
+		for _, op := range ops.PerOperationStrategies {
+			if op.ProbabilisticSampling == nil {
+				continue
+			}
+			operations[op.Operation] = guaranteedThroughput(
+				op.ProbabilisticSampling.SamplingRate,
+				ops.DefaultLowerBoundTracesPerSecond,
+			)
+		}
+		def := guaranteedThroughput(ops.DefaultSamplingProbability, ops.DefaultLowerBoundTracesPerSecond)
+		desc := "PerOperationSampler{default:" + def.Description() + "}"
+		return trace.SamplerFuncWithDescription(desc, func(p trace.SamplingParameters) trace.SamplingDecision {
+			if s, ok := operations[p.Name]; ok {
+				return s.ShouldSample(p)
+			}
+			return def.ShouldSample(p)
+		}), nil
+	}
+	switch r.StrategyType {
+	case strategyProbabilistic:
+		if r.ProbabilisticSampling == nil {
+			return nil, errors.New("missing probabilisticSampling in sampling strategy")
+		}
+		return probabilistic(r.ProbabilisticSampling.SamplingRate), nil
+	case strategyRateLimiting:
+		if r.RateLimitingSampling == nil {
+			return nil, errors.New("missing rateLimitingSampling in sampling strategy")
+		}
+		return trace.RateLimitingSampler(r.RateLimitingSampling.MaxTracesPerSecond), nil
+	}
+	return nil, fmt.Errorf("unknown sampling strategy type %q", r.StrategyType)
+}
+
+// samplerTypeLowerBound is the sampler.type of spans sampled by the lower
+// bound of a per-operation strategy.
+const samplerTypeLowerBound = "lowerbound"
+
+// probabilistic returns a probability sampler whose decisions carry the
+// sampler.type and sampler.param attributes.
+func probabilistic(fraction float64) trace.Sampler {
+	s := trace.ProbabilitySampler(fraction)
+	attrs := []core.KeyValue{
+		trace.SamplerTypeKey.String(trace.SamplerTypeProbabilistic),
+		trace.SamplerParamKey.Float64(fraction),
+	}
+	return trace.SamplerFuncWithDescription(s.Description(), func(p trace.SamplingParameters) trace.SamplingDecision {
+		d := s.ShouldSample(p)
+		if d.Sampled() {
+			d.Attributes = attrs
+		}
+		return d
+	})
+}
+
+// guaranteedThroughput returns a sampler that samples the fraction of the
+// traces and, in addition, at least lowerBound traces per second.
+func guaranteedThroughput(fraction, lowerBound float64) trace.Sampler {
+	prob := probabilistic(fraction)
+	if lowerBound <= 0 {
+		return prob
+	}
+	limiter := trace.RateLimitingSampler(lowerBound)
+	attrs := []core.KeyValue{
+		trace.SamplerTypeKey.String(samplerTypeLowerBound),
+		trace.SamplerParamKey.Float64(lowerBound),
+	}
+	desc := fmt.Sprintf("GuaranteedThroughputSampler{%g,%g}", fraction, lowerBound)
+	return trace.SamplerFuncWithDescription(desc, func(p trace.SamplingParameters) trace.SamplingDecision {
+		if d := prob.ShouldSample(p); d.Sampled() {
+			return d
+		}
+		d := limiter.ShouldSample(p)
+		if d.Sampled() {
+			d.Attributes = attrs
+		}
+		return d
+	})
+}
@@ -0,0 +1,326 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jaeger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/api/core"
+	apitrace "go.opentelemetry.io/api/trace"
+	"go.opentelemetry.io/sdk/trace"
+)
+
+// strategyServer serves a sampling strategy that can be replaced.
+type strategyServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	status   int
+	strategy string
+	service  string
+}
+
+func newStrategyServer(strategy string) *strategyServer {
+	s := &strategyServer{status: http.StatusOK, strategy: strategy}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.service = r.URL.Query().Get("service")
+		w.WriteHeader(s.status)
+		w.Write([]byte(s.strategy))
+	}))
+	return s
+}
+
+func (s *strategyServer) set(status int, strategy string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status, s.strategy = status, strategy
+}
+
+// sampledTraceID is sampled by any non-zero sampling rate,
+// notSampledTraceID only by a rate of 1.
+var (
+	sampledTraceID    = core.TraceID{High: 1}
+	notSampledTraceID = core.TraceID{High: 1<<64 - 1}
+)
+
+func sample(s apitrace.Sampler, name string, traceID core.TraceID) trace.SamplingDecision {
+	return s.ShouldSample(trace.SamplingParameters{Name: name, TraceID: traceID})
+}
+
+func TestRemoteSamplerProbabilistic(t *testing.T) {
+	srv := newStrategyServer(`{"strategyType":"PROBABILISTIC","probabilisticSampling":{"samplingRate":0.5}}`)
+	defer srv.Close()
+
+	rs, err := NewRemoteSampler(RemoteSamplerOptions{
+		Endpoint:    srv.URL + "/sampling",
+		ServiceName: "checkout",
+		OnError:     func(err error) { t.Errorf("unexpected error: %v", err) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Close()
+
+	if srv.service != "checkout" {
+		t.Errorf("requested service: got %q, want %q", srv.service, "checkout")
+	}
+	d := sample(rs, "op", sampledTraceID)
+	if !d.Sampled() {
+		t.Fatal("span not sampled by probabilistic strategy")
+	}
+	if got, want := d.Attributes[0].Value.String, trace.SamplerTypeProbabilistic; got != want {
+		t.Errorf("sampler.type: got %q, want %q", got, want)
+	}
+	if sample(rs, "op", notSampledTraceID).Sampled() {
+		t.Error("span above the sampling rate sampled")
+	}
+}
+
+func TestRemoteSamplerRateLimiting(t *testing.T) {
+	// Older agents encode the strategy type as number.
+	srv := newStrategyServer(`{"strategyType":1,"rateLimitingSampling":{"maxTracesPerSecond":2}}`)
+	defer srv.Close()
+
+	rs, err := NewRemoteSampler(RemoteSamplerOptions{Endpoint: srv.URL, ServiceName: "checkout"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Close()
+
+	sampled := 0
+	for i := 0; i < 10; i++ {
+		if sample(rs, "op", notSampledTraceID).Sampled() {
+			sampled++
+		}
+	}
+	if sampled != 2 {
+		t.Errorf("got %d sampled spans, want 2", sampled)
+	}
+
+	// An unchanged strategy keeps the exhausted rate limiter.
+	if err := rs.Update(); err != nil {
+		t.Fatal(err)
+	}
+	if sample(rs, "op", notSampledTraceID).Sampled() {
+		t.Error("rate limiter reset by unchanged strategy")
+	}
+}
+
+func TestRemoteSamplerPerOperation(t *testing.T) {
+	srv := newStrategyServer(`{
+		"strategyType": "PROBABILISTIC",
+		"probabilisticSampling": {"samplingRate": 1},
+		"operationSampling": {
+			"defaultSamplingProbability": 0,
+			"defaultLowerBoundTracesPerSecond": 1,
+			"perOperationStrategies": [
+				{"operation": "/checkout", "probabilisticSampling": {"samplingRate": 1}}
+			]
+		}
+	}`)
+	defer srv.Close()
+
+	rs, err := NewRemoteSampler(RemoteSamplerOptions{Endpoint: srv.URL, ServiceName: "checkout"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Close()
+
+	for i := 0; i < 3; i++ {
+		if !sample(rs, "/checkout", notSampledTraceID).Sampled() {
+			t.Fatal("/checkout not sampled")
+		}
+	}
+	d := sample(rs, "/healthz", notSampledTraceID)
+	if !d.Sampled() {
+		t.Fatal("first /healthz span not sampled by lower bound")
+	}
+	if got, want := d.Attributes[0].Value.String, "lowerbound"; got != want {
+		t.Errorf("sampler.type: got %q, want %q", got, want)
+	}
+	if sample(rs, "/healthz", notSampledTraceID).Sampled() {
+		t.Error("second /healthz span sampled above lower bound")
+	}
+}
+
+func TestRemoteSamplerFallback(t *testing.T) {
+	srv := newStrategyServer("")
+	defer srv.Close()
+	srv.set(http.StatusInternalServerError, "")
+
+	var errs []error
+	rs, err := NewRemoteSampler(RemoteSamplerOptions{
+		Endpoint:       srv.URL,
+		ServiceName:    "checkout",
+		DefaultSampler: trace.NeverSample(),
+		OnError:        func(err error) { errs = append(errs, err) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Close()
+	if len(errs) != 1 {
+		t.Errorf("got errors %v, want one error", errs)
+	}
+	if sample(rs, "op", sampledTraceID).Sampled() {
+		t.Error("span sampled by default sampler")
+	}
+
+	srv.set(http.StatusOK, `{"strategyType":"PROBABILISTIC","probabilisticSampling":{"samplingRate":1}}`)
+	if err := rs.Update(); err != nil {
+		t.Fatal(err)
+	}
+	if !sample(rs, "op", sampledTraceID).Sampled() {
+		t.Error("span not sampled after strategy was fetched")
+	}
+
+	// Invalid strategies keep the current one.
+	for _, strategy := range []string{
+		`{"strategyType":"ADAPTIVE"}`,
+		`{"strategyType":"RATE_LIMITING"}`,
+		`not json`,
+	} {
+		srv.set(http.StatusOK, strategy)
+		if err := rs.Update(); err == nil {
+			t.Errorf("%s: got no error", strategy)
+		}
+	}
+	srv.Close()
+	if err := rs.Update(); err == nil {
+		t.Error("unreachable endpoint: got no error")
+	}
+	if !sample(rs, "op", sampledTraceID).Sampled() {
+		t.Error("span not sampled after failed updates")
+	}
+}
+
+func TestNewRemoteSamplerOptions(t *testing.T) {
+	if _, err := NewRemoteSampler(RemoteSamplerOptions{ServiceName: "checkout"}); err == nil {
+		t.Error("got no error without endpoint")
+	}
+	if _, err := NewRemoteSampler(RemoteSamplerOptions{Endpoint: "http://localhost:5778/sampling"}); err == nil {
+		t.Error("got no error without service name")
+	}
+}
+
+func TestRemoteSamplerRefresh(t *testing.T) {
+	srv := newStrategyServer(`{"strategyType":"PROBABILISTIC","probabilisticSampling":{"samplingRate":0}}`)
+	defer srv.Close()
+
+	rs, err := NewRemoteSampler(RemoteSamplerOptions{
+		Endpoint:        srv.URL,
+		ServiceName:     "checkout",
+		RefreshInterval: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sample(rs, "op", sampledTraceID).Sampled() {
+		t.Fatal("span sampled with sampling rate 0")
+	}
+
+	srv.set(http.StatusOK, `{"strategyType":"PROBABILISTIC","probabilisticSampling":{"samplingRate":1}}`)
+	deadline := time.Now().Add(5 * time.Second)
+	for !sample(rs, "op", sampledTraceID).Sampled() {
+		if time.Now().After(deadline) {
+			t.Fatal("strategy not refreshed")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	rs.Close()
+	srv.set(http.StatusOK, `{"strategyType":"PROBABILISTIC","probabilisticSampling":{"samplingRate":0}}`)
+	time.Sleep(10 * time.Millisecond)
+	if !sample(rs, "op", sampledTraceID).Sampled() {
+		t.Error("strategy refreshed after Close")
+	}
+}
+
+// newHangingServer returns a server whose requests block until release is
+// closed.
+func newHangingServer(release chan struct{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+}
+
+func TestRemoteSamplerInitialFetchTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := newHangingServer(release)
+	defer srv.Close()
+	defer close(release)
+
+	var errs []error
+	start := time.Now()
+	rs, err := NewRemoteSampler(RemoteSamplerOptions{
+		Endpoint:            srv.URL,
+		ServiceName:         "checkout",
+		DefaultSampler:      trace.NeverSample(),
+		InitialFetchTimeout: 10 * time.Millisecond,
+		OnError:             func(err error) { errs = append(errs, err) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rs.Close()
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("NewRemoteSampler took %v", d)
+	}
+	if len(errs) != 1 {
+		t.Errorf("got errors %v, want one error", errs)
+	}
+	if sample(rs, "op", sampledTraceID).Sampled() {
+		t.Error("span sampled by default sampler")
+	}
+}
+
+func TestRemoteSamplerCloseAbortsUpdate(t *testing.T) {
+	release := make(chan struct{})
+	srv := newHangingServer(release)
+	defer srv.Close()
+	defer close(release)
+
+	rs, err := NewRemoteSampler(RemoteSamplerOptions{
+		Endpoint:            srv.URL,
+		ServiceName:         "checkout",
+		InitialFetchTimeout: time.Millisecond,
+		OnError:             func(error) {},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	errCh := make(chan error, 1)
+	go func() { errCh <- rs.Update() }()
+	time.Sleep(10 * time.Millisecond)
+
+	rs.Close()
+	select {
+	case err := <-errCh:
+		if err == nil {
+			t.Error("aborted Update: got no error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Update not aborted by Close")
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stdout
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/api/core"
+	"go.opentelemetry.io/sdk/trace"
+)
+
+// jsonSpan is the stable JSON representation of a trace.SpanData. IDs are
+// rendered as lowercase hex strings, timestamps in RFC 3339 format with
+// nanosecond precision and the status as its canonical name.
+type jsonSpan struct {
+	TraceID                      string          `json:"traceId"`
+	SpanID                       string          `json:"spanId"`
+	ParentSpanID                 string          `json:"parentSpanId,omitempty"`
+	TraceFlags                   byte            `json:"traceFlags"`
+	SpanKind                     int             `json:"spanKind"`
+	Name                         string          `json:"name"`
+	StartTime                    string          `json:"startTime"`
+	EndTime                      string          `json:"endTime"`
+	Attributes                   []jsonAttribute `json:"attributes,omitempty"`
+	MessageEvents                []jsonEvent     `json:"messageEvents,omitempty"`
+	Links                        []jsonLink      `json:"links,omitempty"`
+	Status                       string          `json:"status"`
+	HasRemoteParent              bool            `json:"hasRemoteParent"`
+	DroppedAttributeCount        int             `json:"droppedAttributeCount"`
+	DroppedMessageEventCount     int             `json:"droppedMessageEventCount"`
+	DroppedLinkCount             int             `json:"droppedLinkCount"`
+	DroppedEventAttributeCount   int             `json:"droppedEventAttributeCount"`
+	DroppedLinkAttributeCount    int             `json:"droppedLinkAttributeCount"`
+	TruncatedAttributeValueCount int             `json:"truncatedAttributeValueCount"`
+	ChildSpanCount               int             `json:"childSpanCount"`
+	TracerName                   string          `json:"tracerName,omitempty"`
+	TracerVersion                string          `json:"tracerVersion,omitempty"`
+	Resource                     []jsonAttribute `json:"resource,omitempty"`
+}
+
+type jsonAttribute struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+type jsonEvent struct {
+	Message    string          `json:"message"`
+	Time       string          `json:"time"`
+	Attributes []jsonAttribute `json:"attributes,omitempty"`
+}
+
+type jsonLink struct {
+	TraceID    string          `json:"traceId"`
+	SpanID     string          `json:"spanId"`
+	Attributes []jsonAttribute `json:"attributes,omitempty"`
+}
+
+func newJSONSpan(data *trace.SpanData) *jsonSpan {
+	js := &jsonSpan{
+		TraceID:                      data.SpanContext.TraceIDString(),
+		SpanID:                       data.SpanContext.SpanIDString(),
+		TraceFlags:                   data.SpanContext.TraceFlags,
+		SpanKind:                     data.SpanKind,
+		Name:                         data.Name,
+		StartTime:                    formatTime(data.StartTime),
+		EndTime:                      formatTime(data.EndTime),
+		Attributes:                   jsonAttributes(data.Attributes),
+		Status:                       data.Status.String(),
+		HasRemoteParent:              data.HasRemoteParent,
+		DroppedAttributeCount:        data.DroppedAttributeCount,
+		DroppedMessageEventCount:     data.DroppedMessageEventCount,
+		DroppedLinkCount:             data.DroppedLinkCount,
+		DroppedEventAttributeCount:   data.DroppedEventAttributeCount,
+		DroppedLinkAttributeCount:    data.DroppedLinkAttributeCount,
+		TruncatedAttributeValueCount: data.TruncatedAttributeValueCount,
+		ChildSpanCount:               data.ChildSpanCount,
+		TracerName:                   data.TracerName,
+		TracerVersion:                data.TracerVersion,
+		Resource:                     jsonAttributes(data.Resource.Attributes()),
+	}
+	if data.ParentSpanID != 0 {
+		js.ParentSpanID = spanIDString(data.ParentSpanID)
+	}
+	for _, e := range data.MessageEvents {
+		js.MessageEvents = append(js.MessageEvents, jsonEvent{
+			Message:    e.Message,
+			Time:       formatTime(e.Time),
+			Attributes: jsonAttributes(e.Attributes),
+		})
+	}
+	for _, l := range data.Links {
+		js.Links = append(js.Links, jsonLink{
+			TraceID:    l.TraceIDString(),
+			SpanID:     l.SpanIDString(),
+			Attributes: jsonAttributes(l.Attributes),
+		})
+	}
+	return js
+}
+
+func jsonAttributes(kvs []core.KeyValue) []jsonAttribute {
+	if len(kvs) == 0 {
+		return nil
+	}
+	attrs := make([]jsonAttribute, 0, len(kvs))
+	for _, kv := range kvs {
+		attrs = append(attrs, jsonAttribute{
+			Key:   kv.Key.Name,
+			Value: jsonValue(kv.Value),
+		})
+	}
+	return attrs
+}
+
+func jsonValue(v core.Value) interface{} {
+	switch v.Type {
+	case core.BOOL:
+		return v.Bool
+	case core.INT32, core.INT64:
+		return v.Int64
+	case core.UINT32, core.UINT64:
+		return v.Uint64
+	case core.FLOAT32, core.FLOAT64:
+		return v.Float64
+	case core.STRING:
+		return v.String
+	case core.BYTES:
+		return v.Bytes
+	}
+	return nil
+}
+
+// appendText appends a compact single-line representation of the span
+// to buf, for example:
+//
+//	2019-10-01T12:00:00.000000001Z /foo trace=0102...0f10 span=0102030405060708 dur=1.5ms status=OK key="value"
+func appendText(buf []byte, data *trace.SpanData) []byte {
+	buf = append(buf, formatTime(data.StartTime)...)
+	buf = append(buf, ' ')
+	buf = append(buf, data.Name...)
+	buf = append(buf, " trace="...)
+	buf = append(buf, data.SpanContext.TraceIDString()...)
+	buf = append(buf, " span="...)
+	buf = append(buf, data.SpanContext.SpanIDString()...)
+	if data.ParentSpanID != 0 {
+		buf = append(buf, " parent="...)
+		buf = append(buf, spanIDString(data.ParentSpanID)...)
+	}
+	buf = append(buf, " dur="...)
+	buf = append(buf, data.EndTime.Sub(data.StartTime).String()...)
+	buf = append(buf, " status="...)
+	buf = append(buf, data.Status.String()...)
+	for _, kv := range data.Attributes {
+		buf = append(buf, ' ')
+		buf = appendTextAttribute(buf, kv)
+	}
+	if n := len(data.MessageEvents); n > 0 {
+		buf = append(buf, " events="...)
+		buf = strconv.AppendInt(buf, int64(n), 10)
+	}
+	if n := len(data.Links); n > 0 {
+		buf = append(buf, " links="...)
+		buf = strconv.AppendInt(buf, int64(n), 10)
+	}
+	return buf
+}
+
+func appendTextAttribute(buf []byte, kv core.KeyValue) []byte {
+	buf = append(buf, kv.Key.Name...)
+	buf = append(buf, '=')
+	switch kv.Value.Type {
+	case core.STRING:
+		return strconv.AppendQuote(buf, kv.Value.String)
+	case core.BYTES:
+		return strconv.AppendQuote(buf, string(kv.Value.Bytes))
+	}
+	return append(buf, kv.Value.Emit()...)
+}
+
+func formatTime(t time.Time) string {
+	return t.Format(time.RFC3339Nano)
+}
+
+func spanIDString(id uint64) string {
+	return fmt.Sprintf("%.16x", id)
+}
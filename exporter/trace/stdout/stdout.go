@@ -16,46 +16,110 @@ package stdout
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
+	"log"
 	"os"
+	"sync"
 
 	"go.opentelemetry.io/sdk/trace"
 )
 
+// Format selects how spans are rendered by the exporter.
+type Format int
+
+const (
+	// FormatJSON renders every span as a single JSON document. This is the
+	// default format.
+	FormatJSON Format = iota
+
+	// FormatText renders every span as a compact, single-line,
+	// human-readable record.
+	FormatText
+)
+
 // Options are the options to be used when initializing a stdout exporter.
 type Options struct {
 	// PrettyPrint will pretty the json representation of the span,
 	// making it print "pretty". Default is false.
+	// It is ignored unless Format is FormatJSON.
 	PrettyPrint bool
+
+	// Format is the output format of the spans. Default is FormatJSON.
+	Format Format
+
+	// Writer is the destination of the exported spans.
+	// Default is os.Stdout.
+	Writer io.Writer
+
+	// OnError is the hook to be called when a span cannot be
+	// converted or written.
+	// If no custom hook is set, errors are logged.
+	// Optional.
+	OnError func(err error)
 }
 
 // Exporter is an implementation of trace.Exporter that writes spans to stdout.
 type Exporter struct {
-	pretty       bool
+	pretty  bool
+	format  Format
+	onError func(err error)
+
+	mu           sync.Mutex // serializes writes to outputWriter
 	outputWriter io.Writer
 }
 
+var _ trace.Exporter = (*Exporter)(nil)
+
+// NewExporter returns a trace.Exporter implementation that writes the
+// collected spans to o.Writer, or to os.Stdout if no writer is given.
 func NewExporter(o Options) (*Exporter, error) {
+	if o.Format != FormatJSON && o.Format != FormatText {
+		return nil, fmt.Errorf("unknown stdout exporter format: %d", o.Format)
+	}
+	w := o.Writer
+	if w == nil {
+		w = os.Stdout
+	}
+	onError := o.OnError
+	if onError == nil {
+		onError = func(err error) {
+			log.Printf("Error when exporting span to stdout: %v", err)
+		}
+	}
 	return &Exporter{
 		pretty:       o.PrettyPrint,
-		outputWriter: os.Stdout,
+		format:       o.Format,
+		onError:      onError,
+		outputWriter: w,
 	}, nil
 }
 
-// ExportSpan writes a SpanData in json format to stdout.
+// ExportSpan writes a SpanData to the configured writer in the configured
+// format.
 func (e *Exporter) ExportSpan(data *trace.SpanData) {
-	var jsonSpan []byte
-	var err error
-	if e.pretty {
-		jsonSpan, err = json.MarshalIndent(data, "", "\t")
-	} else {
-		jsonSpan, err = json.Marshal(data)
+	var out []byte
+	switch e.format {
+	case FormatText:
+		out = appendText(nil, data)
+	default:
+		var err error
+		if e.pretty {
+			out, err = json.MarshalIndent(newJSONSpan(data), "", "\t")
+		} else {
+			out, err = json.Marshal(newJSONSpan(data))
+		}
+		if err != nil {
+			e.onError(fmt.Errorf("error converting spanData to json: %v", err))
+			return
+		}
 	}
+	out = append(out, '\n')
+
+	e.mu.Lock()
+	_, err := e.outputWriter.Write(out)
+	e.mu.Unlock()
 	if err != nil {
-		// ignore writer failures for now
-		_, _ = e.outputWriter.Write([]byte("Error converting spanData to json: " + err.Error()))
-		return
+		e.onError(err)
 	}
-	// ignore writer failures for now
-	_, _ = e.outputWriter.Write(append(jsonSpan, byte('\n')))
 }
@@ -16,41 +16,32 @@ package stdout
 
 import (
 	"bytes"
-	"encoding/json"
+	"errors"
 	"testing"
 	"time"
 
 	"google.golang.org/grpc/codes"
 
 	"go.opentelemetry.io/api/core"
+	"go.opentelemetry.io/sdk/resource"
 	"go.opentelemetry.io/sdk/trace"
 )
 
-func TestExporter_ExportSpan(t *testing.T) {
-	exporter, err := NewExporter(Options{})
-	if err != nil {
-		t.Errorf("Error constructing stdout exporter %s", err)
-	}
-
-	// override output writer for testing
-	var b bytes.Buffer
-	exporter.outputWriter = &b
-
-	// setup test span
-	now := time.Now()
+func testSpanData(now time.Time) *trace.SpanData {
 	traceID := core.TraceID{High: 0x0102030405060708, Low: 0x090a0b0c0d0e0f10}
 	spanID := uint64(0x0102030405060708)
 	keyValue := "value"
 	doubleValue := float64(123.456)
 
-	testSpan := &trace.SpanData{
+	return &trace.SpanData{
 		SpanContext: core.SpanContext{
 			TraceID: traceID,
 			SpanID:  spanID,
 		},
-		Name:      "/foo",
-		StartTime: now,
-		EndTime:   now,
+		ParentSpanID: 0x0a0b0c0d0e0f1011,
+		Name:         "/foo",
+		StartTime:    now,
+		EndTime:      now.Add(1500 * time.Microsecond),
 		Attributes: []core.KeyValue{
 			{
 				Key:   core.Key{Name: "key"},
@@ -61,41 +52,124 @@ func TestExporter_ExportSpan(t *testing.T) {
 				Value: core.Value{Type: core.FLOAT64, Float64: doubleValue},
 			},
 		},
-		Status: codes.Unknown,
+		MessageEvents: []trace.Event{
+			{
+				Message: "event",
+				Time:    now,
+				Attributes: []core.KeyValue{
+					{
+						Key:   core.Key{Name: "int"},
+						Value: core.Value{Type: core.INT64, Int64: 7},
+					},
+				},
+			},
+		},
+		Status:                       codes.Unknown,
+		DroppedEventAttributeCount:   1,
+		DroppedLinkAttributeCount:    2,
+		TruncatedAttributeValueCount: 3,
+		Resource:                     resource.New(resource.ServiceName.String("checkout")),
 	}
-	exporter.ExportSpan(testSpan)
+}
 
-	expectedSerializedNow, _ := json.Marshal(now)
+func TestExporter_ExportSpan(t *testing.T) {
+	var b bytes.Buffer
+	exporter, err := NewExporter(Options{Writer: &b})
+	if err != nil {
+		t.Errorf("Error constructing stdout exporter %s", err)
+	}
+
+	now := time.Date(2019, time.October, 1, 12, 0, 0, 1, time.UTC)
+	exporter.ExportSpan(testSpanData(now))
 
 	got := b.String()
-	expectedOutput := `{"SpanContext":{` +
-		`"TraceID":{"High":72623859790382856,"Low":651345242494996240},` +
-		`"SpanID":72623859790382856,"TraceFlags":0},` +
-		`"ParentSpanID":0,` +
-		`"SpanKind":0,` +
-		`"Name":"/foo",` +
-		`"StartTime":` + string(expectedSerializedNow) + "," +
-		`"EndTime":` + string(expectedSerializedNow) + "," +
-		`"Attributes":[` +
-		`{` +
-		`"Key":{"Name":"key"},` +
-		`"Value":{"Type":8,"Bool":false,"Int64":0,"Uint64":0,"Float64":0,"String":"value","Bytes":null}` +
-		`},` +
-		`{` +
-		`"Key":{"Name":"double"},` +
-		`"Value":{"Type":7,"Bool":false,"Int64":0,"Uint64":0,"Float64":123.456,"String":"","Bytes":null}` +
-		`}` +
+	expectedOutput := `{"traceId":"0102030405060708090a0b0c0d0e0f10",` +
+		`"spanId":"0102030405060708",` +
+		`"parentSpanId":"0a0b0c0d0e0f1011",` +
+		`"traceFlags":0,` +
+		`"spanKind":0,` +
+		`"name":"/foo",` +
+		`"startTime":"2019-10-01T12:00:00.000000001Z",` +
+		`"endTime":"2019-10-01T12:00:00.001500001Z",` +
+		`"attributes":[` +
+		`{"key":"key","value":"value"},` +
+		`{"key":"double","value":123.456}` +
+		`],` +
+		`"messageEvents":[` +
+		`{"message":"event","time":"2019-10-01T12:00:00.000000001Z","attributes":[{"key":"int","value":7}]}` +
 		`],` +
-		`"MessageEvents":null,` +
-		`"Links":null,` +
-		`"Status":2,` +
-		`"HasRemoteParent":false,` +
-		`"DroppedAttributeCount":0,` +
-		`"DroppedMessageEventCount":0,` +
-		`"DroppedLinkCount":0,` +
-		`"ChildSpanCount":0}` + "\n"
+		`"status":"Unknown",` +
+		`"hasRemoteParent":false,` +
+		`"droppedAttributeCount":0,` +
+		`"droppedMessageEventCount":0,` +
+		`"droppedLinkCount":0,` +
+		`"droppedEventAttributeCount":1,` +
+		`"droppedLinkAttributeCount":2,` +
+		`"truncatedAttributeValueCount":3,` +
+		`"childSpanCount":0,` +
+		`"resource":[{"key":"service.name","value":"checkout"}]}` + "\n"
+
+	if got != expectedOutput {
+		t.Errorf("Want: %v but got: %v", expectedOutput, got)
+	}
+}
+
+func TestExporter_ExportSpanText(t *testing.T) {
+	var b bytes.Buffer
+	exporter, err := NewExporter(Options{Writer: &b, Format: FormatText})
+	if err != nil {
+		t.Errorf("Error constructing stdout exporter %s", err)
+	}
+
+	now := time.Date(2019, time.October, 1, 12, 0, 0, 1, time.UTC)
+	exporter.ExportSpan(testSpanData(now))
+
+	got := b.String()
+	expectedOutput := `2019-10-01T12:00:00.000000001Z /foo ` +
+		`trace=0102030405060708090a0b0c0d0e0f10 ` +
+		`span=0102030405060708 ` +
+		`parent=0a0b0c0d0e0f1011 ` +
+		`dur=1.5ms status=Unknown key="value" double=123.456 events=1` + "\n"
 
 	if got != expectedOutput {
 		t.Errorf("Want: %v but got: %v", expectedOutput, got)
 	}
 }
+
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestExporter_OnError(t *testing.T) {
+	var (
+		exporter *Exporter
+		errs     []error
+	)
+	exporter, err := NewExporter(Options{
+		Writer: errWriter{},
+		OnError: func(err error) {
+			errs = append(errs, err)
+			// The hook may use the exporter.
+			if len(errs) == 1 {
+				exporter.ExportSpan(testSpanData(time.Now()))
+			}
+		},
+	})
+	if err != nil {
+		t.Errorf("Error constructing stdout exporter %s", err)
+	}
+
+	exporter.ExportSpan(testSpanData(time.Now()))
+
+	if len(errs) != 2 || errs[0].Error() != "write failed" {
+		t.Errorf("Want write errors to be reported, got: %v", errs)
+	}
+}
+
+func TestNewExporterWithUnknownFormat(t *testing.T) {
+	if _, err := NewExporter(Options{Format: Format(42)}); err == nil {
+		t.Errorf("Expected error while creating exporter with unknown format")
+	}
+}
@@ -0,0 +1,342 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tree
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/api/core"
+	"go.opentelemetry.io/sdk/trace"
+)
+
+const (
+	defaultOrphanTimeout = 10 * time.Second
+	defaultGroupTimeout  = time.Second
+)
+
+// Options are the options to be used when initializing a tree exporter.
+type Options struct {
+	// Writer is the destination of the printed traces.
+	// Default is os.Stdout.
+	Writer io.Writer
+
+	// OrphanTimeout is the time a trace is buffered after its first span
+	// was received if its local root span never ends. After the timeout
+	// the buffered spans are printed as they are.
+	// Default is 10 seconds.
+	OrphanTimeout time.Duration
+
+	// GroupTimeout is the time a trace is still buffered after a local
+	// root span ended, so that the spans of further local roots of the
+	// same trace, such as concurrent requests with the same remote parent,
+	// are printed in the same tree. Every span received in the meantime
+	// restarts the timeout.
+	// Default is 1 second.
+	GroupTimeout time.Duration
+
+	// OnError is the hook to be called when a trace cannot be written.
+	// If no custom hook is set, errors are logged.
+	// Optional.
+	OnError func(err error)
+}
+
+// Exporter is an implementation of trace.Exporter that buffers spans per
+// trace and prints every trace as a tree once its local root spans ended.
+type Exporter struct {
+	orphanTimeout time.Duration
+	groupTimeout  time.Duration
+	onError       func(err error)
+
+	mu     sync.Mutex
+	w      io.Writer
+	traces map[core.TraceID]*pendingTrace
+	closed bool
+}
+
+type pendingTrace struct {
+	spans []*trace.SpanData
+	timer *time.Timer
+	// rootEnded is set once a local root span of the trace was exported.
+	rootEnded bool
+}
+
+var _ trace.Exporter = (*Exporter)(nil)
+
+// NewExporter returns a trace.Exporter implementation that prints the
+// collected traces as trees.
+func NewExporter(o Options) (*Exporter, error) {
+	w := o.Writer
+	if w == nil {
+		w = os.Stdout
+	}
+	timeout := o.OrphanTimeout
+	if timeout <= 0 {
+		timeout = defaultOrphanTimeout
+	}
+	groupTimeout := o.GroupTimeout
+	if groupTimeout <= 0 {
+		groupTimeout = defaultGroupTimeout
+	}
+	onError := o.OnError
+	if onError == nil {
+		onError = func(err error) {
+			log.Printf("Error when printing trace tree: %v", err)
+		}
+	}
+	return &Exporter{
+		orphanTimeout: timeout,
+		groupTimeout:  groupTimeout,
+		onError:       onError,
+		w:             w,
+		traces:        make(map[core.TraceID]*pendingTrace),
+	}, nil
+}
+
+// ExportSpan buffers the SpanData until the group timeout expired after a
+// local root span of its trace was exported, or the orphan timeout expires,
+// and then prints the trace.
+func (e *Exporter) ExportSpan(data *trace.SpanData) {
+	id := data.SpanContext.TraceID
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed {
+		return
+	}
+	pt, ok := e.traces[id]
+	if !ok {
+		pt = &pendingTrace{}
+		pt.timer = time.AfterFunc(e.orphanTimeout, func() {
+			e.flushTrace(id, pt)
+		})
+		e.traces[id] = pt
+	}
+	pt.spans = append(pt.spans, data)
+	if isLocalRoot(data) {
+		pt.rootEnded = true
+	}
+	if pt.rootEnded {
+		pt.timer.Stop()
+		pt.timer.Reset(e.groupTimeout)
+	}
+}
+
+// Flush prints all buffered traces, including those whose local root
+// span has not been exported yet.
+func (e *Exporter) Flush() {
+	e.mu.Lock()
+	errs := e.flushAll()
+	e.mu.Unlock()
+	e.report(errs)
+}
+
+// Close prints all buffered traces and stops the exporter. Spans exported
+// after Close are discarded.
+func (e *Exporter) Close() {
+	e.mu.Lock()
+	errs := e.flushAll()
+	e.closed = true
+	e.mu.Unlock()
+	e.report(errs)
+}
+
+// flushAll prints all buffered traces and returns the write errors.
+// e.mu must be held.
+func (e *Exporter) flushAll() []error {
+	ids := make([]core.TraceID, 0, len(e.traces))
+	for id := range e.traces {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return e.traces[ids[i]].spans[0].StartTime.Before(e.traces[ids[j]].spans[0].StartTime)
+	})
+	var errs []error
+	for _, id := range ids {
+		pt := e.traces[id]
+		pt.timer.Stop()
+		delete(e.traces, id)
+		if err := e.print(pt.spans); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// flushTrace is invoked by the timer of pt.
+func (e *Exporter) flushTrace(id core.TraceID, pt *pendingTrace) {
+	e.mu.Lock()
+	// The trace may have been printed and replaced in the meantime.
+	if e.traces[id] != pt {
+		e.mu.Unlock()
+		return
+	}
+	delete(e.traces, id)
+	err := e.print(pt.spans)
+	e.mu.Unlock()
+	if err != nil {
+		e.report([]error{err})
+	}
+}
+
+// print writes the tree of spans. e.mu must be held.
+func (e *Exporter) print(spans []*trace.SpanData) error {
+	_, err := io.WriteString(e.w, formatTrace(spans))
+	return err
+}
+
+// report passes errs to the OnError hook. e.mu must not be held, so that
+// the hook may use the exporter.
+func (e *Exporter) report(errs []error) {
+	for _, err := range errs {
+		e.onError(err)
+	}
+}
+
+func isLocalRoot(data *trace.SpanData) bool {
+	return data.ParentSpanID == 0 || data.HasRemoteParent
+}
+
+type node struct {
+	data     *trace.SpanData
+	children []*node
+	// cyclic is set on a top-level node whose parent is among the spans
+	// but is the span itself or one of its descendants.
+	cyclic bool
+}
+
+// formatTrace renders all spans of a single trace as an indented tree.
+// Spans whose parent is not among spans are printed as top-level nodes, as
+// is one span of every parent cycle.
+func formatTrace(spans []*trace.SpanData) string {
+	nodes := make(map[uint64]*node, len(spans))
+	for _, sd := range spans {
+		nodes[sd.SpanContext.SpanID] = &node{data: sd}
+	}
+	var roots []*node
+	origin := spans[0].StartTime
+	for _, sd := range spans {
+		n := nodes[sd.SpanContext.SpanID]
+		if parent, ok := nodes[sd.ParentSpanID]; ok && sd.ParentSpanID != 0 && !sd.HasRemoteParent {
+			if sd.ParentSpanID == sd.SpanContext.SpanID {
+				n.cyclic = true
+				roots = append(roots, n)
+			} else {
+				parent.children = append(parent.children, n)
+			}
+		} else {
+			roots = append(roots, n)
+		}
+		if sd.StartTime.Before(origin) {
+			origin = sd.StartTime
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("=== TRACE ")
+	b.WriteString(spans[0].SpanContext.TraceIDString())
+	b.WriteString(" (")
+	b.WriteString(strconv.Itoa(len(spans)))
+	b.WriteString(" spans)")
+	sortNodes(roots)
+	// All spans of a trace produced by one process share its resource.
+	res := spans[0].Resource
+	if len(roots) > 0 {
+		res = roots[0].data.Resource
+	}
+	for _, kv := range res.Attributes() {
+		fmt.Fprintf(&b, " %s=%s", kv.Key.Name, formatValue(kv.Value))
+	}
+	b.WriteByte('\n')
+	visited := make(map[*node]bool, len(nodes))
+	for _, n := range roots {
+		writeNode(&b, n, origin, 0, visited)
+	}
+	// Spans of a parent cycle are not reachable from any root.
+	rest := make([]*node, 0, len(nodes)-len(visited))
+	for _, n := range nodes {
+		if !visited[n] {
+			rest = append(rest, n)
+		}
+	}
+	sortNodes(rest)
+	for _, n := range rest {
+		if !visited[n] {
+			n.cyclic = true
+			writeNode(&b, n, origin, 0, visited)
+		}
+	}
+	return b.String()
+}
+
+func sortNodes(nodes []*node) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].data.StartTime.Before(nodes[j].data.StartTime)
+	})
+}
+
+// writeNode writes n and its descendants. Nodes in visited are skipped, so
+// that every node is written once even if the parents form a cycle.
+func writeNode(b *strings.Builder, n *node, origin time.Time, depth int, visited map[*node]bool) {
+	if visited[n] {
+		return
+	}
+	visited[n] = true
+	sd := n.data
+	indent := strings.Repeat("    ", depth)
+	fmt.Fprintf(b, "%s--- %s (+%s, %s, %s)", indent, sd.Name,
+		sd.StartTime.Sub(origin), sd.EndTime.Sub(sd.StartTime), sd.Status)
+	if depth == 0 && sd.ParentSpanID != 0 {
+		if sd.HasRemoteParent {
+			fmt.Fprintf(b, " remote parent=%.16x", sd.ParentSpanID)
+		} else if n.cyclic {
+			fmt.Fprintf(b, " cyclic parent=%.16x", sd.ParentSpanID)
+		} else {
+			fmt.Fprintf(b, " orphan parent=%.16x", sd.ParentSpanID)
+		}
+	}
+	for _, kv := range sd.Attributes {
+		fmt.Fprintf(b, " %s=%s", kv.Key.Name, formatValue(kv.Value))
+	}
+	b.WriteByte('\n')
+	for _, ev := range sd.MessageEvents {
+		fmt.Fprintf(b, "%s    * %s (+%s)", indent, ev.Message, ev.Time.Sub(origin))
+		for _, kv := range ev.Attributes {
+			fmt.Fprintf(b, " %s=%s", kv.Key.Name, formatValue(kv.Value))
+		}
+		b.WriteByte('\n')
+	}
+	sortNodes(n.children)
+	for _, c := range n.children {
+		writeNode(b, c, origin, depth+1, visited)
+	}
+}
+
+func formatValue(v core.Value) string {
+	switch v.Type {
+	case core.STRING:
+		return strconv.Quote(v.String)
+	case core.BYTES:
+		return strconv.Quote(string(v.Bytes))
+	}
+	return v.Emit()
+}
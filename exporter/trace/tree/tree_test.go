@@ -0,0 +1,229 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tree
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+
+	"go.opentelemetry.io/api/core"
+	"go.opentelemetry.io/api/key"
+	"go.opentelemetry.io/sdk/resource"
+	"go.opentelemetry.io/sdk/trace"
+)
+
+var traceID = core.TraceID{High: 0x0102030405060708, Low: 0x090a0b0c0d0e0f10}
+
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.Write(p)
+}
+
+func (s *syncBuffer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.String()
+}
+
+func spanData(name string, spanID, parentID uint64, start time.Time, d time.Duration) *trace.SpanData {
+	return &trace.SpanData{
+		SpanContext: core.SpanContext{
+			TraceID:    traceID,
+			SpanID:     spanID,
+			TraceFlags: core.TraceFlagsSampled,
+		},
+		ParentSpanID: parentID,
+		Name:         name,
+		StartTime:    start,
+		EndTime:      start.Add(d),
+		Status:       codes.OK,
+	}
+}
+
+// waitForOutput waits up to a second until something was written to b.
+func waitForOutput(b *syncBuffer) string {
+	deadline := time.Now().Add(time.Second)
+	for b.String() == "" && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	return b.String()
+}
+
+func TestExporter_ExportSpan(t *testing.T) {
+	var b syncBuffer
+	exporter, err := NewExporter(Options{Writer: &b, GroupTimeout: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Error constructing tree exporter %s", err)
+	}
+	defer exporter.Close()
+
+	now := time.Date(2019, time.October, 1, 12, 0, 0, 0, time.UTC)
+	db := spanData("db.query", 3, 2, now.Add(2*time.Millisecond), 5*time.Millisecond)
+	db.Attributes = []core.KeyValue{key.New("db.statement").String("SELECT 1")}
+	db.MessageEvents = []trace.Event{{Message: "cache miss", Time: now.Add(3 * time.Millisecond)}}
+	render := spanData("render", 4, 1, now.Add(9*time.Millisecond), time.Millisecond)
+	render.Status = codes.Internal
+
+	exporter.ExportSpan(db)
+	exporter.ExportSpan(spanData("lookup", 2, 1, now.Add(time.Millisecond), 7*time.Millisecond))
+	exporter.ExportSpan(render)
+	if got := b.String(); got != "" {
+		t.Fatalf("trace printed before root span ended: %q", got)
+	}
+	root := spanData("/checkout", 1, 0, now, 10*time.Millisecond)
+	root.Resource = resource.New(resource.ServiceName.String("checkout"))
+	exporter.ExportSpan(root)
+	if got := b.String(); got != "" {
+		t.Fatalf("trace printed before group timeout: %q", got)
+	}
+
+	want := "=== TRACE 0102030405060708090a0b0c0d0e0f10 (4 spans) service.name=\"checkout\"\n" +
+		"--- /checkout (+0s, 10ms, OK)\n" +
+		"    --- lookup (+1ms, 7ms, OK)\n" +
+		"        --- db.query (+2ms, 5ms, OK) db.statement=\"SELECT 1\"\n" +
+		"            * cache miss (+3ms)\n" +
+		"    --- render (+9ms, 1ms, Internal)\n"
+	if got := waitForOutput(&b); got != want {
+		t.Errorf("Want:\n%s\nbut got:\n%s", want, got)
+	}
+}
+
+func TestExporter_OrphanTimeout(t *testing.T) {
+	var b syncBuffer
+	exporter, err := NewExporter(Options{
+		Writer:        &b,
+		OrphanTimeout: 10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Error constructing tree exporter %s", err)
+	}
+	defer exporter.Close()
+
+	now := time.Date(2019, time.October, 1, 12, 0, 0, 0, time.UTC)
+	exporter.ExportSpan(spanData("child", 2, 1, now, time.Millisecond))
+
+	want := "=== TRACE 0102030405060708090a0b0c0d0e0f10 (1 spans)\n" +
+		"--- child (+0s, 1ms, OK) orphan parent=0000000000000001\n"
+	if got := waitForOutput(&b); got != want {
+		t.Errorf("Want:\n%s\nbut got:\n%s", want, got)
+	}
+}
+
+func TestExporter_Close(t *testing.T) {
+	var b syncBuffer
+	exporter, err := NewExporter(Options{Writer: &b})
+	if err != nil {
+		t.Fatalf("Error constructing tree exporter %s", err)
+	}
+
+	now := time.Date(2019, time.October, 1, 12, 0, 0, 0, time.UTC)
+	remote := spanData("server", 2, 1, now, time.Millisecond)
+	remote.HasRemoteParent = true
+	exporter.ExportSpan(spanData("inner", 3, 2, now, time.Millisecond))
+	exporter.Close()
+	exporter.ExportSpan(remote)
+
+	want := "=== TRACE 0102030405060708090a0b0c0d0e0f10 (1 spans)\n" +
+		"--- inner (+0s, 1ms, OK) orphan parent=0000000000000002\n"
+	if got := b.String(); got != want {
+		t.Errorf("Want:\n%s\nbut got:\n%s", want, got)
+	}
+}
+
+func TestExporter_GroupTimeout(t *testing.T) {
+	var b syncBuffer
+	exporter, err := NewExporter(Options{Writer: &b, GroupTimeout: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Error constructing tree exporter %s", err)
+	}
+	defer exporter.Close()
+
+	// Two requests of the same trace received from a remote parent.
+	now := time.Date(2019, time.October, 1, 12, 0, 0, 0, time.UTC)
+	first := spanData("GET /a", 2, 1, now, time.Millisecond)
+	first.HasRemoteParent = true
+	second := spanData("GET /b", 3, 1, now.Add(2*time.Millisecond), time.Millisecond)
+	second.HasRemoteParent = true
+	exporter.ExportSpan(first)
+	exporter.ExportSpan(spanData("db.query", 4, 3, now.Add(2*time.Millisecond), time.Millisecond))
+	exporter.ExportSpan(second)
+
+	want := "=== TRACE 0102030405060708090a0b0c0d0e0f10 (3 spans)\n" +
+		"--- GET /a (+0s, 1ms, OK) remote parent=0000000000000001\n" +
+		"--- GET /b (+2ms, 1ms, OK) remote parent=0000000000000001\n" +
+		"    --- db.query (+2ms, 1ms, OK)\n"
+	if got := waitForOutput(&b); got != want {
+		t.Errorf("Want:\n%s\nbut got:\n%s", want, got)
+	}
+}
+
+type errWriter struct{}
+
+func (errWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
+
+func TestExporter_OnErrorWithoutLock(t *testing.T) {
+	var (
+		exporter *Exporter
+		errs     int
+	)
+	exporter, err := NewExporter(Options{
+		Writer: errWriter{},
+		OnError: func(err error) {
+			errs++
+			// The hook may use the exporter.
+			exporter.Flush()
+		},
+	})
+	if err != nil {
+		t.Fatalf("Error constructing tree exporter %s", err)
+	}
+
+	now := time.Date(2019, time.October, 1, 12, 0, 0, 0, time.UTC)
+	exporter.ExportSpan(spanData("/checkout", 1, 0, now, time.Millisecond))
+	exporter.Close()
+	if errs != 1 {
+		t.Errorf("OnError: got %d calls, want 1", errs)
+	}
+}
+
+func TestFormatTrace_ParentCycle(t *testing.T) {
+	now := time.Date(2019, time.October, 1, 12, 0, 0, 0, time.UTC)
+	got := formatTrace([]*trace.SpanData{
+		spanData("self", 1, 1, now, time.Millisecond),
+		spanData("a", 2, 3, now.Add(time.Millisecond), 2*time.Millisecond),
+		spanData("b", 3, 2, now.Add(2*time.Millisecond), time.Millisecond),
+	})
+
+	want := "=== TRACE 0102030405060708090a0b0c0d0e0f10 (3 spans)\n" +
+		"--- self (+0s, 1ms, OK) cyclic parent=0000000000000001\n" +
+		"--- a (+1ms, 2ms, OK) cyclic parent=0000000000000003\n" +
+		"    --- b (+2ms, 1ms, OK)\n"
+	if got != want {
+		t.Errorf("Want:\n%s\nbut got:\n%s", want, got)
+	}
+}
@@ -20,4 +20,5 @@ require (
 	golang.org/x/tools v0.0.0-20190920225731-5eefd052ad72
 	google.golang.org/api v0.9.0
 	google.golang.org/grpc v1.22.1
+	gopkg.in/yaml.v2 v2.4.0
 )
@@ -30,6 +30,9 @@ import (
 	"go.opentelemetry.io/api/trace"
 )
 
+// instrumentationName is the name of the tracer used by this package.
+const instrumentationName = "go.opentelemetry.io/plugin/httptrace"
+
 var (
 	HTTPStatus     = key.New("http.status")
 	HTTPHeaderMIME = key.New("http.mime")
@@ -42,6 +45,8 @@ type clientTracer struct {
 	context.Context
 	httptrace.ClientTrace
 
+	tracer trace.Tracer
+
 	levels map[string]trace.Span
 	root   trace.Span
 	mtx    sync.Mutex
@@ -50,6 +55,7 @@ type clientTracer struct {
 func newClientTracer(ctx context.Context) *clientTracer {
 	ct := &clientTracer{
 		Context: ctx,
+		tracer:  trace.GlobalProvider().GetTracer(instrumentationName, ""),
 		levels:  make(map[string]trace.Span),
 	}
 	ct.open("http.request")
@@ -57,7 +63,7 @@ func newClientTracer(ctx context.Context) *clientTracer {
 }
 
 func (ct *clientTracer) open(name string, attrs ...core.KeyValue) {
-	_, sp := trace.Start(ct.Context, name, trace.WithAttributes(attrs...))
+	_, sp := ct.tracer.Start(ct.Context, name, trace.WithAttributes(attrs...))
 	ct.mtx.Lock()
 	defer ct.mtx.Unlock()
 	if ct.root == nil {
@@ -0,0 +1,214 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resource
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+
+	"go.opentelemetry.io/api/core"
+	"go.opentelemetry.io/api/key"
+)
+
+// EnvAttributes is the environment variable holding resource attributes
+// as a comma separated list of key=value pairs.
+const EnvAttributes = "OTEL_RESOURCE_ATTRIBUTES"
+
+// Detector detects attributes of the resource the program runs as.
+type Detector interface {
+	// Detect returns the detected resource. A detector that does not
+	// apply to the environment returns an empty resource and no error.
+	Detect(ctx context.Context) (*Resource, error)
+}
+
+// Detect runs the detectors in order and merges their results. If several
+// detectors report the same key, the value of the first one is used. A
+// failing detector does not stop the others; the merged result of the
+// successful detectors is returned together with the first error.
+func Detect(ctx context.Context, detectors ...Detector) (*Resource, error) {
+	var (
+		res      *Resource
+		firstErr error
+	)
+	for _, d := range detectors {
+		r, err := d.Detect(ctx)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		res = Merge(res, r)
+	}
+	return res, firstErr
+}
+
+// Default detects the resource from the environment variable, the host,
+// the process and the container, in this order of precedence.
+func Default(ctx context.Context) (*Resource, error) {
+	return Detect(ctx, Env{}, Host{}, Process{}, Container{})
+}
+
+// Env detects resource attributes from the EnvAttributes environment
+// variable, for example
+//
+//	OTEL_RESOURCE_ATTRIBUTES="service.name=checkout,deployment.environment=prod"
+//
+// All values are strings.
+type Env struct {
+	// LookupEnv looks up an environment variable.
+	// Default is os.LookupEnv.
+	LookupEnv func(key string) (string, bool)
+}
+
+// Detect implements Detector.
+func (d Env) Detect(context.Context) (*Resource, error) {
+	lookup := d.LookupEnv
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	v, ok := lookup(EnvAttributes)
+	if !ok || strings.TrimSpace(v) == "" {
+		return New(), nil
+	}
+	var kvs []core.KeyValue
+	for _, pair := range strings.Split(v, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("%s: missing value in %q", EnvAttributes, pair)
+		}
+		k, val := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+		if k == "" {
+			return nil, fmt.Errorf("%s: missing key in %q", EnvAttributes, pair)
+		}
+		kvs = append(kvs, key.New(k).String(val))
+	}
+	return New(kvs...), nil
+}
+
+// Host detects the host name, the operating system and the architecture.
+type Host struct {
+	// Hostname returns the host name.
+	// Default is os.Hostname.
+	Hostname func() (string, error)
+}
+
+// Detect implements Detector.
+func (d Host) Detect(context.Context) (*Resource, error) {
+	hostname := d.Hostname
+	if hostname == nil {
+		hostname = os.Hostname
+	}
+	name, err := hostname()
+	if err != nil {
+		return nil, fmt.Errorf("detecting host name: %v", err)
+	}
+	return New(
+		HostName.String(name),
+		OSType.String(runtime.GOOS),
+		HostArch.String(runtime.GOARCH),
+	), nil
+}
+
+// Process detects the process ID, the executable name and the Go runtime.
+type Process struct {
+	// Getpid returns the process ID.
+	// Default is os.Getpid.
+	Getpid func() int
+
+	// Executable returns the path of the executable.
+	// Default is os.Executable.
+	Executable func() (string, error)
+}
+
+// Detect implements Detector.
+func (d Process) Detect(context.Context) (*Resource, error) {
+	getpid, executable := d.Getpid, d.Executable
+	if getpid == nil {
+		getpid = os.Getpid
+	}
+	if executable == nil {
+		executable = os.Executable
+	}
+	path, err := executable()
+	if err != nil {
+		return nil, fmt.Errorf("detecting executable: %v", err)
+	}
+	return New(
+		ProcessPID.Int(getpid()),
+		ProcessExecutableName.String(filepath.Base(path)),
+		ProcessRuntimeName.String("go"),
+		ProcessRuntimeVersion.String(runtime.Version()),
+	), nil
+}
+
+// cgroupPath is the file the container ID is parsed from.
+const cgroupPath = "/proc/self/cgroup"
+
+// containerIDPattern matches the container ID at the end of a cgroup
+// path, such as /docker/<id>, /kubepods/.../<id> or
+// /system.slice/docker-<id>.scope.
+var containerIDPattern = regexp.MustCompile(`([0-9a-f]{64})(?:\.scope)?$`)
+
+// Container detects the ID of the container the process runs in from the
+// cgroup file of the process. Outside of a container it detects nothing.
+type Container struct {
+	// ReadFile reads the file with the given name.
+	// Default is ioutil.ReadFile.
+	ReadFile func(name string) ([]byte, error)
+}
+
+// Detect implements Detector.
+func (d Container) Detect(context.Context) (*Resource, error) {
+	readFile := d.ReadFile
+	if readFile == nil {
+		readFile = ioutil.ReadFile
+	}
+	b, err := readFile(cgroupPath)
+	if os.IsNotExist(err) {
+		return New(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("detecting container: %v", err)
+	}
+	if id := containerID(b); id != "" {
+		return New(ContainerID.String(id)), nil
+	}
+	return New(), nil
+}
+
+// containerID returns the first container ID found in the lines of a
+// cgroup file, each of the form hierarchy-ID:controllers:path.
+func containerID(cgroup []byte) string {
+	s := bufio.NewScanner(bytes.NewReader(cgroup))
+	for s.Scan() {
+		parts := strings.SplitN(s.Text(), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if m := containerIDPattern.FindStringSubmatch(parts[2]); m != nil {
+			return m[1]
+		}
+	}
+	return ""
+}
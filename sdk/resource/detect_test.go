@@ -0,0 +1,187 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resource
+
+import (
+	"context"
+	"errors"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"go.opentelemetry.io/api/core"
+	"go.opentelemetry.io/api/key"
+)
+
+func lookupEnv(env map[string]string) func(string) (string, bool) {
+	return func(k string) (string, bool) {
+		v, ok := env[k]
+		return v, ok
+	}
+}
+
+func TestEnv(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		value   string
+		want    []core.KeyValue
+		wantErr bool
+	}{
+		{name: "unset", want: []core.KeyValue{}},
+		{
+			name:  "attributes",
+			value: " service.name = checkout ,deployment.environment=prod,empty=",
+			want: []core.KeyValue{
+				DeploymentEnvironment.String("prod"),
+				key.New("empty").String(""),
+				ServiceName.String("checkout"),
+			},
+		},
+		{name: "missing value", value: "service.name", wantErr: true},
+		{name: "missing key", value: "=checkout", wantErr: true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			env := map[string]string{}
+			if c.value != "" {
+				env[EnvAttributes] = c.value
+			}
+			res, err := Env{LookupEnv: lookupEnv(env)}.Detect(context.Background())
+			if c.wantErr {
+				if err == nil {
+					t.Errorf("got resource %v, want error", res.Attributes())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(res.Attributes(), c.want); diff != "" {
+				t.Errorf("-got +want %s", diff)
+			}
+		})
+	}
+}
+
+func TestHost(t *testing.T) {
+	res, err := Host{Hostname: func() (string, error) { return "host1", nil }}.Detect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []core.KeyValue{
+		HostArch.String(runtime.GOARCH),
+		HostName.String("host1"),
+		OSType.String(runtime.GOOS),
+	}
+	if diff := cmp.Diff(res.Attributes(), want); diff != "" {
+		t.Errorf("-got +want %s", diff)
+	}
+
+	_, err = Host{Hostname: func() (string, error) { return "", errors.New("boom") }}.Detect(context.Background())
+	if err == nil {
+		t.Error("got no error for failing host name lookup")
+	}
+}
+
+func TestProcess(t *testing.T) {
+	res, err := Process{
+		Getpid:     func() int { return 42 },
+		Executable: func() (string, error) { return "/usr/local/bin/checkout", nil },
+	}.Detect(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []core.KeyValue{
+		ProcessExecutableName.String("checkout"),
+		ProcessPID.Int(42),
+		ProcessRuntimeName.String("go"),
+		ProcessRuntimeVersion.String(runtime.Version()),
+	}
+	if diff := cmp.Diff(res.Attributes(), want); diff != "" {
+		t.Errorf("-got +want %s", diff)
+	}
+}
+
+func TestContainer(t *testing.T) {
+	const id = "a4d6c2f3b1e0a4d6c2f3b1e0a4d6c2f3b1e0a4d6c2f3b1e0a4d6c2f3b1e0abcd"
+	for _, c := range []struct {
+		name   string
+		cgroup string
+		err    error
+		want   []core.KeyValue
+	}{
+		{
+			name:   "docker",
+			cgroup: "12:pids:/docker/" + id + "\n11:cpu,cpuacct:/docker/" + id + "\n",
+			want:   []core.KeyValue{ContainerID.String(id)},
+		},
+		{
+			name:   "kubernetes",
+			cgroup: "1:name=systemd:/kubepods/besteffort/pod5e0f7a4c/" + id + "\n",
+			want:   []core.KeyValue{ContainerID.String(id)},
+		},
+		{
+			name:   "systemd scope",
+			cgroup: "0::/system.slice/docker-" + id + ".scope\n",
+			want:   []core.KeyValue{ContainerID.String(id)},
+		},
+		{
+			name:   "host",
+			cgroup: "0::/user.slice/user-1000.slice/session-2.scope\n",
+			want:   []core.KeyValue{},
+		},
+		{
+			name: "no cgroup file",
+			err:  os.ErrNotExist,
+			want: []core.KeyValue{},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			readFile := func(name string) ([]byte, error) {
+				if name != cgroupPath {
+					t.Errorf("read %q, want %q", name, cgroupPath)
+				}
+				return []byte(c.cgroup), c.err
+			}
+			res, err := Container{ReadFile: readFile}.Detect(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(res.Attributes(), c.want); diff != "" {
+				t.Errorf("-got +want %s", diff)
+			}
+		})
+	}
+}
+
+type detectorFunc func() (*Resource, error)
+
+func (f detectorFunc) Detect(context.Context) (*Resource, error) { return f() }
+
+func TestDetect(t *testing.T) {
+	boom := errors.New("boom")
+	res, err := Detect(context.Background(),
+		detectorFunc(func() (*Resource, error) { return New(kv11), nil }),
+		detectorFunc(func() (*Resource, error) { return nil, boom }),
+		detectorFunc(func() (*Resource, error) { return New(kv12, kv21), nil }),
+	)
+	if err != boom {
+		t.Errorf("error: got %v, want %v", err, boom)
+	}
+	if diff := cmp.Diff(res.Attributes(), []core.KeyValue{kv11, kv21}); diff != "" {
+		t.Errorf("-got +want %s", diff)
+	}
+}
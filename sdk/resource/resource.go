@@ -0,0 +1,141 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package resource provides an immutable description of the entity
+// producing telemetry, such as a service, the host and the process it runs
+// in and the deployment it belongs to.
+package resource // import "go.opentelemetry.io/sdk/resource"
+
+import (
+	"encoding/json"
+	"sort"
+
+	"go.opentelemetry.io/api/core"
+	"go.opentelemetry.io/api/key"
+)
+
+// Standard attribute keys describing a resource.
+var (
+	ServiceName       = key.New("service.name")
+	ServiceNamespace  = key.New("service.namespace")
+	ServiceInstanceID = key.New("service.instance.id")
+	ServiceVersion    = key.New("service.version")
+
+	HostName = key.New("host.name")
+	HostID   = key.New("host.id")
+	HostArch = key.New("host.arch")
+
+	OSType = key.New("os.type")
+
+	ProcessPID            = key.New("process.pid")
+	ProcessExecutableName = key.New("process.executable.name")
+	ProcessRuntimeName    = key.New("process.runtime.name")
+	ProcessRuntimeVersion = key.New("process.runtime.version")
+
+	ContainerID = key.New("container.id")
+
+	DeploymentEnvironment = key.New("deployment.environment")
+)
+
+// Resource is an immutable set of attributes describing the entity
+// producing telemetry. A nil *Resource is valid and empty.
+type Resource struct {
+	labels map[core.Key]core.Value
+}
+
+// New creates a resource from the given attributes. If a key is given more
+// than once, the last value is used. Attributes with an undefined key are
+// ignored.
+func New(kvs ...core.KeyValue) *Resource {
+	r := &Resource{labels: make(map[core.Key]core.Value, len(kvs))}
+	for _, kv := range kvs {
+		if !kv.Key.Defined() {
+			continue
+		}
+		r.labels[kv.Key] = kv.Value
+	}
+	return r
+}
+
+// Merge creates a resource with the attributes of both a and b. If a key is
+// present in both, the value of a is used.
+func Merge(a, b *Resource) *Resource {
+	if b.Len() == 0 {
+		return a
+	}
+	if a.Len() == 0 {
+		return b
+	}
+	r := &Resource{labels: make(map[core.Key]core.Value, len(a.labels)+len(b.labels))}
+	for k, v := range b.labels {
+		r.labels[k] = v
+	}
+	for k, v := range a.labels {
+		r.labels[k] = v
+	}
+	return r
+}
+
+// Attributes returns the attributes of the resource sorted by key.
+func (r *Resource) Attributes() []core.KeyValue {
+	if r == nil {
+		return nil
+	}
+	kvs := make([]core.KeyValue, 0, len(r.labels))
+	for k, v := range r.labels {
+		kvs = append(kvs, core.KeyValue{Key: k, Value: v})
+	}
+	sort.Slice(kvs, func(i, j int) bool {
+		return kvs[i].Key.Name < kvs[j].Key.Name
+	})
+	return kvs
+}
+
+// Value returns the value of the attribute with the given key and whether
+// it is present.
+func (r *Resource) Value(k core.Key) (core.Value, bool) {
+	if r == nil {
+		return core.Value{}, false
+	}
+	v, ok := r.labels[k]
+	return v, ok
+}
+
+// Len returns the number of attributes of the resource.
+func (r *Resource) Len() int {
+	if r == nil {
+		return 0
+	}
+	return len(r.labels)
+}
+
+// MarshalJSON encodes the attributes of the resource as a JSON array
+// sorted by key.
+func (r *Resource) MarshalJSON() ([]byte, error) {
+	kvs := r.Attributes()
+	if kvs == nil {
+		kvs = []core.KeyValue{}
+	}
+	return json.Marshal(kvs)
+}
+
+// UnmarshalJSON decodes a resource encoded by MarshalJSON.
+func (r *Resource) UnmarshalJSON(b []byte) error {
+	var kvs []core.KeyValue
+	if err := json.Unmarshal(b, &kvs); err != nil {
+		return err
+	}
+	*r = *New(kvs...)
+	return nil
+}
@@ -0,0 +1,96 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package resource
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"go.opentelemetry.io/api/core"
+	"go.opentelemetry.io/api/key"
+)
+
+var (
+	kv11 = key.New("k1").String("v11")
+	kv12 = key.New("k1").String("v12")
+	kv21 = key.New("k2").String("v21")
+	kv31 = key.New("k3").String("v31")
+)
+
+func TestNew(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		in   []core.KeyValue
+		want []core.KeyValue
+	}{
+		{name: "empty", in: nil, want: []core.KeyValue{}},
+		{name: "sorted", in: []core.KeyValue{kv21, kv11}, want: []core.KeyValue{kv11, kv21}},
+		{name: "last value wins", in: []core.KeyValue{kv11, kv12}, want: []core.KeyValue{kv12}},
+		{name: "undefined key", in: []core.KeyValue{{}, kv11}, want: []core.KeyValue{kv11}},
+	} {
+		if diff := cmp.Diff(New(c.in...).Attributes(), c.want); diff != "" {
+			t.Errorf("%s: -got +want %s", c.name, diff)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		a, b *Resource
+		want []core.KeyValue
+	}{
+		{name: "nil", a: nil, b: nil, want: nil},
+		{name: "a only", a: New(kv11), b: nil, want: []core.KeyValue{kv11}},
+		{name: "b only", a: New(), b: New(kv21), want: []core.KeyValue{kv21}},
+		{name: "disjoint", a: New(kv11, kv31), b: New(kv21), want: []core.KeyValue{kv11, kv21, kv31}},
+		{name: "a wins", a: New(kv11), b: New(kv12, kv21), want: []core.KeyValue{kv11, kv21}},
+	} {
+		if diff := cmp.Diff(Merge(c.a, c.b).Attributes(), c.want); diff != "" {
+			t.Errorf("%s: -got +want %s", c.name, diff)
+		}
+	}
+}
+
+func TestValue(t *testing.T) {
+	r := New(kv11)
+	if v, ok := r.Value(kv11.Key); !ok || v.String != kv11.Value.String {
+		t.Errorf("Value(%s): got %v, %v", kv11.Key.Name, v, ok)
+	}
+	if _, ok := r.Value(kv21.Key); ok {
+		t.Errorf("Value(%s): got present, want missing", kv21.Key.Name)
+	}
+	var nilRes *Resource
+	if _, ok := nilRes.Value(kv11.Key); ok || nilRes.Len() != 0 {
+		t.Error("nil resource is not empty")
+	}
+}
+
+func TestJSON(t *testing.T) {
+	r := New(kv21, kv11, key.New("pid").Int64(42))
+	b, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Resource
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(got.Attributes(), r.Attributes()); diff != "" {
+		t.Errorf("JSON round trip: -got +want %s", diff)
+	}
+}
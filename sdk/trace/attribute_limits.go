@@ -0,0 +1,80 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"unicode/utf8"
+
+	"go.opentelemetry.io/api/core"
+)
+
+// valueLengthLimit returns the max length of string and byte values of
+// attributes with the key, or zero or less if they are not truncated.
+func (c *Config) valueLengthLimit(k core.Key) int {
+	if limit, ok := c.MaxAttributeValueLengthPerKey[k]; ok {
+		return limit
+	}
+	return c.MaxAttributeValueLength
+}
+
+// truncateAttribute returns kv with its string or byte value truncated to
+// the length limit of its key and reports whether it was truncated. Strings
+// are truncated at a rune boundary. Truncated values are copied, so that
+// the original value is not retained.
+func truncateAttribute(cfg *Config, kv core.KeyValue) (core.KeyValue, bool) {
+	limit := cfg.valueLengthLimit(kv.Key)
+	if limit <= 0 {
+		return kv, false
+	}
+	switch kv.Value.Type {
+	case core.STRING:
+		if len(kv.Value.String) <= limit {
+			return kv, false
+		}
+		for limit > 0 && !utf8.RuneStart(kv.Value.String[limit]) {
+			limit--
+		}
+		kv.Value.String = string([]byte(kv.Value.String[:limit]))
+		return kv, true
+	case core.BYTES:
+		if len(kv.Value.Bytes) <= limit {
+			return kv, false
+		}
+		kv.Value.Bytes = append([]byte(nil), kv.Value.Bytes[:limit]...)
+		return kv, true
+	}
+	return kv, false
+}
+
+// limitAttributes returns a copy of the first max attributes with their
+// values truncated, the number of dropped attributes and the number of
+// truncated values.
+func limitAttributes(cfg *Config, attrs []core.KeyValue, max int) (limited []core.KeyValue, dropped, truncated int) {
+	if len(attrs) == 0 {
+		return attrs, 0, 0
+	}
+	if len(attrs) > max {
+		dropped = len(attrs) - max
+		attrs = attrs[:max]
+	}
+	limited = make([]core.KeyValue, len(attrs))
+	for i, kv := range attrs {
+		var ok bool
+		if limited[i], ok = truncateAttribute(cfg, kv); ok {
+			truncated++
+		}
+	}
+	return limited, dropped, truncated
+}
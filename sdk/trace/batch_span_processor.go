@@ -16,15 +16,23 @@ package trace
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"go.opentelemetry.io/sdk/trace/internal/diskqueue"
 )
 
 const (
 	defaultMaxQueueSize         = 2048
 	defaultScheduledDelayMillis = time.Duration(5000 * time.Millisecond)
 	defaultMaxExportBatchSize   = 512
+	defaultExportTimeout        = 30 * time.Second
+	defaultMaxConcurrentExports = 1
 )
 
 var (
@@ -36,6 +44,7 @@ type BatchSpanProcessorOption func(o *BatchSpanProcessorOptions)
 type BatchSpanProcessorOptions struct {
 	// MaxQueueSize is the maximum queue size to buffer spans for delayed processing. If the
 	// queue gets full it drops the spans. Use BlockOnQueueFull to change this behavior.
+	// MaxExportBatchSize is limited to MaxQueueSize.
 	// The default value of MaxQueueSize is 2048.
 	MaxQueueSize int
 
@@ -50,26 +59,119 @@ type BatchSpanProcessorOptions struct {
 	// The default value of MaxExportBatchSize is 512.
 	MaxExportBatchSize int
 
+	// EarlyExport processes the queue without waiting for ScheduledDelayMillis as
+	// soon as MaxExportBatchSize spans were added to it, so that bursts of spans
+	// are exported before the queue gets full.
+	// The default value of EarlyExport is false.
+	EarlyExport bool
+
+	// MaxConcurrentExports is the maximum number of batches passed to the exporter
+	// at the same time. If it is greater than 1 the exporter must be safe for
+	// concurrent use. If PersistentQueueDir is set, up to MaxConcurrentExports
+	// batches are read from the on-disk queue and exported at the same time, and
+	// they are removed from the queue in order once they were exported.
+	// The default value of MaxConcurrentExports is 1.
+	MaxConcurrentExports int
+
 	// BlockOnQueueFull blocks onEnd() and onStart() method if the queue is full
 	// AND if BlockOnQueueFull is set to true.
 	// Blocking option should be used carefully as it can severely affect the performance of an
 	// application.
+	// It is ignored if PersistentQueueDir is set.
 	BlockOnQueueFull bool
+
+	// PersistentQueueDir is the directory of an on-disk queue buffering spans for delayed
+	// processing. If it is set, spans are persisted in append-only segment files instead of
+	// being held in memory, and spans that were not exported when the process exited are
+	// exported by the next BatchSpanProcessor using the same directory. Spans are removed
+	// from the queue only after they were passed to the exporter, so they are delivered at
+	// least once. MaxQueueSize is ignored, use PersistentQueueMaxBytes instead.
+	PersistentQueueDir string
+
+	// PersistentQueueMaxBytes is the maximum size in bytes of the spans in the on-disk
+	// queue that were not exported yet. If the queue gets full it drops the spans.
+	// The default value of PersistentQueueMaxBytes is 256 MiB.
+	PersistentQueueMaxBytes int64
+
+	// ExportTimeout is the time after which the context passed to the exporter for
+	// a batch is canceled.
+	// The default value of ExportTimeout is 30 seconds.
+	ExportTimeout time.Duration
+
+	// OnDropStateChange is called with true when the processor starts dropping
+	// spans because the queue is full, and with false when a span is queued
+	// again after spans were dropped. It is called synchronously from OnEnd and
+	// should return quickly. Spans that could not be written to the on-disk
+	// queue do not change the drop state.
+	OnDropStateChange func(dropping bool)
+}
+
+// BatchSpanProcessorStats holds the number of spans handled by a
+// BatchSpanProcessor since it was created.
+type BatchSpanProcessorStats struct {
+	// Enqueued is the number of spans added to the queue.
+	Enqueued uint64
+	// Exported is the number of spans passed to the exporter without error.
+	Exported uint64
+	// Dropped is the number of spans dropped because the queue was full.
+	Dropped uint64
+	// WriteFailed is the number of spans that could not be written to the
+	// on-disk queue because of an encoding or I/O error.
+	WriteFailed uint64
+	// Failed is the number of spans in batches the exporter returned an
+	// error for.
+	Failed uint64
 }
 
 // BatchSpanProcessor implements SpanProcessor interfaces. It is used by
 // exporters to receive SpanData asynchronously.
 // Use BatchSpanProcessorOptions to change the behavior of the processor.
+// Only sampled spans are exported; spans that a sampler decided to record
+// without sampling them, see apitrace.RecordOnly, are skipped.
+// Export failures are passed to the ErrorHandler.
 type BatchSpanProcessor struct {
-	exporter BatchExporter
+	// The counters are accessed atomically and kept first for 64-bit
+	// alignment.
+	enqueued    uint64
+	exported    uint64
+	dropped     uint64
+	writeFailed uint64
+	failed      uint64
+
+	// appended is the number of spans appended to the on-disk queue since
+	// it was last processed. It is accessed atomically.
+	appended uint64
+
+	exporter SpanBatcher
 	o        BatchSpanProcessorOptions
 
-	queue   chan *SpanData
-	dropped uint32
+	queue chan *SpanData
+	dq    *diskqueue.Queue
+
+	// exportSem bounds the number of concurrent exports and exportWait
+	// waits for them. They are only used if MaxConcurrentExports is
+	// greater than 1.
+	exportSem  chan struct{}
+	exportWait sync.WaitGroup
+
+	// dropping is 1 while spans are being dropped. dropMu serializes
+	// the calls of OnDropStateChange.
+	dropping uint32
+	dropMu   sync.Mutex
+
+	// shutdownCtx is the context of the first ShutdownContext call. It is
+	// set before stopCh is closed.
+	shutdownCtx context.Context
+	stopOnce    sync.Once
+	stopCh      chan struct{}
+	stopped     chan struct{}
 
-	stopWait sync.WaitGroup
-	stopOnce sync.Once
-	stopCh   chan struct{}
+	// batchCh is signaled when a full batch was added to the queue.
+	batchCh chan struct{}
+
+	// flushCh receives a channel that is closed once the queue was
+	// processed.
+	flushCh chan chan struct{}
 }
 
 var _ SpanProcessor = (*BatchSpanProcessor)(nil)
@@ -82,38 +184,82 @@ func NewBatchSpanProcessor(exporter BatchExporter, opts ...BatchSpanProcessorOpt
 	if exporter == nil {
 		return nil, errNilExporter
 	}
+	return NewBatchSpanProcessorFromBatcher(BatcherFromBatchExporter(exporter), opts...)
+}
+
+// NewBatchSpanProcessorFromBatcher creates a new instance of
+// BatchSpanProcessor for a given context-aware exporter. It returns an
+// error if exporter is nil.
+func NewBatchSpanProcessorFromBatcher(exporter SpanBatcher, opts ...BatchSpanProcessorOption) (*BatchSpanProcessor, error) {
+	if exporter == nil {
+		return nil, errNilExporter
+	}
 
 	o := BatchSpanProcessorOptions{
 		ScheduledDelayMillis: defaultScheduledDelayMillis,
 		MaxQueueSize:         defaultMaxQueueSize,
 		MaxExportBatchSize:   defaultMaxExportBatchSize,
+		ExportTimeout:        defaultExportTimeout,
+		MaxConcurrentExports: defaultMaxConcurrentExports,
 	}
 	for _, opt := range opts {
 		opt(&o)
 	}
+	if o.ExportTimeout <= 0 {
+		o.ExportTimeout = defaultExportTimeout
+	}
+	if o.PersistentQueueDir == "" && o.MaxExportBatchSize > o.MaxQueueSize {
+		o.MaxExportBatchSize = o.MaxQueueSize
+	}
 	bsp := &BatchSpanProcessor{
 		exporter: exporter,
 		o:        o,
 	}
 
-	bsp.queue = make(chan *SpanData, bsp.o.MaxQueueSize)
+	if o.PersistentQueueDir != "" {
+		dq, err := diskqueue.Open(o.PersistentQueueDir, diskqueue.Options{
+			MaxSize: o.PersistentQueueMaxBytes,
+		})
+		if err != nil {
+			return nil, err
+		}
+		bsp.dq = dq
+	} else {
+		bsp.queue = make(chan *SpanData, bsp.o.MaxQueueSize)
+		if bsp.o.MaxConcurrentExports > 1 {
+			bsp.exportSem = make(chan struct{}, bsp.o.MaxConcurrentExports)
+		}
+	}
 
 	bsp.stopCh = make(chan struct{})
+	bsp.stopped = make(chan struct{})
+	bsp.flushCh = make(chan chan struct{})
+	bsp.batchCh = make(chan struct{}, 1)
 
 	//Start timer to export metrics
 	ticker := time.NewTicker(bsp.o.ScheduledDelayMillis)
 	go func(ctx context.Context) {
 		defer ticker.Stop()
-		bsp.stopWait.Add(1)
 		for {
 			select {
 			case <-bsp.stopCh:
-				bsp.processQueue()
-				close(bsp.queue)
-				bsp.stopWait.Done()
+				bsp.processQueue(bsp.shutdownCtx)
+				bsp.exportWait.Wait()
+				if bsp.dq != nil {
+					_ = bsp.dq.Close()
+				} else {
+					close(bsp.queue)
+				}
+				close(bsp.stopped)
 				return
 			case <-ticker.C:
-				bsp.processQueue()
+				bsp.processQueue(context.Background())
+			case <-bsp.batchCh:
+				bsp.processQueue(context.Background())
+			case done := <-bsp.flushCh:
+				bsp.processQueue(context.Background())
+				bsp.exportWait.Wait()
+				close(done)
 			}
 		}
 	}(context.Background())
@@ -125,18 +271,69 @@ func NewBatchSpanProcessor(exporter BatchExporter, opts ...BatchSpanProcessorOpt
 func (bsp *BatchSpanProcessor) OnStart(sd *SpanData) {
 }
 
-// OnEnd method enqueues SpanData for later processing.
+// OnEnd method enqueues SpanData for later processing. Spans that are
+// recorded but not sampled are not exported.
 func (bsp *BatchSpanProcessor) OnEnd(sd *SpanData) {
+	if !sd.SpanContext.IsSampled() {
+		return
+	}
 	bsp.enqueue(sd)
 }
 
-// Shutdown flushes the queue and waits until all spans are processed.
+// Shutdown flushes the queue and waits until all spans are processed,
+// without a deadline; use ShutdownContext to bound the wait.
 // It only executes once. Subsequent call does nothing.
 func (bsp *BatchSpanProcessor) Shutdown() {
+	_ = bsp.ShutdownContext(context.Background())
+}
+
+// ShutdownContext flushes the queue and waits until all spans are
+// processed or ctx is done, in which case it returns ctx.Err(). The
+// remaining spans are exported with contexts derived from ctx, so an
+// exporter honouring the context stops exporting them once ctx is done.
+// Subsequent calls only wait for the first one to complete.
+func (bsp *BatchSpanProcessor) ShutdownContext(ctx context.Context) error {
 	bsp.stopOnce.Do(func() {
+		bsp.shutdownCtx = ctx
 		close(bsp.stopCh)
-		bsp.stopWait.Wait()
 	})
+	select {
+	case <-bsp.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// ForceFlush exports the queued spans and flushes the exporter if it
+// implements Flusher. It does nothing after Shutdown, which exports the
+// queued spans itself.
+func (bsp *BatchSpanProcessor) ForceFlush(ctx context.Context) error {
+	done := make(chan struct{})
+	select {
+	case bsp.flushCh <- done:
+	case <-bsp.stopCh:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return flushExporter(ctx, bsp.exporter)
+}
+
+// Stats returns the number of spans handled by the processor so far.
+func (bsp *BatchSpanProcessor) Stats() BatchSpanProcessorStats {
+	return BatchSpanProcessorStats{
+		Enqueued:    atomic.LoadUint64(&bsp.enqueued),
+		Exported:    atomic.LoadUint64(&bsp.exported),
+		Dropped:     atomic.LoadUint64(&bsp.dropped),
+		WriteFailed: atomic.LoadUint64(&bsp.writeFailed),
+		Failed:      atomic.LoadUint64(&bsp.failed),
+	}
 }
 
 func WithMaxQueueSize(size int) BatchSpanProcessorOption {
@@ -157,13 +354,88 @@ func WithScheduleDelayMillis(delay time.Duration) BatchSpanProcessorOption {
 	}
 }
 
+func WithMaxConcurrentExports(n int) BatchSpanProcessorOption {
+	return func(o *BatchSpanProcessorOptions) {
+		o.MaxConcurrentExports = n
+	}
+}
+
+func WithEarlyExport() BatchSpanProcessorOption {
+	return func(o *BatchSpanProcessorOptions) {
+		o.EarlyExport = true
+	}
+}
+
 func WithBlocking() BatchSpanProcessorOption {
 	return func(o *BatchSpanProcessorOptions) {
 		o.BlockOnQueueFull = true
 	}
 }
 
-func (bsp *BatchSpanProcessor) processQueue() {
+func WithPersistentQueue(dir string) BatchSpanProcessorOption {
+	return func(o *BatchSpanProcessorOptions) {
+		o.PersistentQueueDir = dir
+	}
+}
+
+func WithPersistentQueueMaxBytes(size int64) BatchSpanProcessorOption {
+	return func(o *BatchSpanProcessorOptions) {
+		o.PersistentQueueMaxBytes = size
+	}
+}
+
+func WithExportTimeout(timeout time.Duration) BatchSpanProcessorOption {
+	return func(o *BatchSpanProcessorOptions) {
+		o.ExportTimeout = timeout
+	}
+}
+
+func WithDropNotifier(f func(dropping bool)) BatchSpanProcessorOption {
+	return func(o *BatchSpanProcessorOptions) {
+		o.OnDropStateChange = f
+	}
+}
+
+// export passes the batch to the exporter with a context derived from ctx
+// that is canceled after the export timeout. The error of the exporter is
+// passed to the ErrorHandler and returned.
+func (bsp *BatchSpanProcessor) export(ctx context.Context, batch []*SpanData) error {
+	ctx, cancel := context.WithTimeout(ctx, bsp.o.ExportTimeout)
+	defer cancel()
+	if err := bsp.exporter.ExportSpans(ctx, batch); err != nil {
+		atomic.AddUint64(&bsp.failed, uint64(len(batch)))
+		handleError(fmt.Errorf("exporting batch of %d spans: %v", len(batch), err))
+		return err
+	}
+	atomic.AddUint64(&bsp.exported, uint64(len(batch)))
+	return nil
+}
+
+// dispatch exports the batch on a new goroutine if concurrent exports are
+// enabled, waiting while MaxConcurrentExports batches are being exported.
+// Otherwise it exports the batch before it returns.
+func (bsp *BatchSpanProcessor) dispatch(ctx context.Context, batch []*SpanData) {
+	if bsp.exportSem == nil {
+		_ = bsp.export(ctx, batch)
+		return
+	}
+	bsp.exportSem <- struct{}{}
+	bsp.exportWait.Add(1)
+	go func() {
+		defer func() {
+			<-bsp.exportSem
+			bsp.exportWait.Done()
+		}()
+		_ = bsp.export(ctx, batch)
+	}()
+}
+
+// processQueue exports the queued spans with contexts derived from ctx.
+func (bsp *BatchSpanProcessor) processQueue(ctx context.Context) {
+	if bsp.dq != nil {
+		bsp.processDiskQueue(ctx)
+		return
+	}
 	batch := make([]*SpanData, 0, bsp.o.MaxExportBatchSize)
 	for {
 		var sd *SpanData
@@ -179,31 +451,152 @@ func (bsp *BatchSpanProcessor) processQueue() {
 		}
 		if ok {
 			if len(batch) >= bsp.o.MaxExportBatchSize {
-				bsp.exporter.ExportSpans(batch)
-				batch = batch[:0]
+				bsp.dispatch(ctx, batch)
+				batch = make([]*SpanData, 0, bsp.o.MaxExportBatchSize)
 			}
 		} else {
 			if len(batch) > 0 {
-				bsp.exporter.ExportSpans(batch)
+				bsp.dispatch(ctx, batch)
 			}
 			break
 		}
 	}
 }
 
+// processDiskQueue exports all spans of the on-disk queue in batches, up
+// to MaxConcurrentExports of them at the same time. Batches are committed,
+// and hence removed from the queue, in order after they were exported. If
+// an export fails, that batch and the ones following it are kept and
+// exported again with the next processing of the queue.
+func (bsp *BatchSpanProcessor) processDiskQueue(ctx context.Context) {
+	atomic.StoreUint64(&bsp.appended, 0)
+	workers := bsp.o.MaxConcurrentExports
+	if workers < 1 {
+		workers = 1
+	}
+	for {
+		var (
+			batches   [][]*SpanData
+			positions []diskqueue.Position
+		)
+		for len(batches) < workers {
+			records, pos, err := bsp.dq.Read(bsp.o.MaxExportBatchSize)
+			if err != nil || len(records) == 0 {
+				break
+			}
+			batch := make([]*SpanData, 0, len(records))
+			for _, r := range records {
+				sd := new(SpanData)
+				// Records that cannot be decoded are skipped.
+				if json.Unmarshal(r, sd) == nil {
+					batch = append(batch, sd)
+				}
+			}
+			batches = append(batches, batch)
+			positions = append(positions, pos)
+		}
+		if len(batches) == 0 {
+			return
+		}
+
+		errs := make([]error, len(batches))
+		var wg sync.WaitGroup
+		for i, batch := range batches {
+			if len(batch) == 0 {
+				continue
+			}
+			if len(batches) == 1 {
+				errs[i] = bsp.export(ctx, batch)
+				break
+			}
+			wg.Add(1)
+			go func(i int, batch []*SpanData) {
+				defer wg.Done()
+				errs[i] = bsp.export(ctx, batch)
+			}(i, batch)
+		}
+		wg.Wait()
+
+		for i, pos := range positions {
+			if errs[i] != nil {
+				bsp.dq.Rewind()
+				return
+			}
+			if err := bsp.dq.Commit(pos); err != nil {
+				return
+			}
+		}
+	}
+}
+
 func (bsp *BatchSpanProcessor) enqueue(sd *SpanData) {
+	if bsp.dq != nil {
+		record, err := json.Marshal(sd)
+		if err == nil {
+			err = bsp.dq.Append(record)
+		}
+		switch err {
+		case nil:
+			bsp.countEnqueue(true)
+			if bsp.o.EarlyExport && atomic.AddUint64(&bsp.appended, 1) >= uint64(bsp.o.MaxExportBatchSize) {
+				bsp.signalBatch()
+			}
+		case diskqueue.ErrFull:
+			bsp.countEnqueue(false)
+		default:
+			atomic.AddUint64(&bsp.writeFailed, 1)
+			handleError(fmt.Errorf("writing span to disk queue: %v", err))
+		}
+		return
+	}
 	if bsp.o.BlockOnQueueFull {
 		bsp.queue <- sd
+		bsp.countEnqueue(true)
 	} else {
-		var ok bool
 		select {
 		case bsp.queue <- sd:
-			ok = true
+			bsp.countEnqueue(true)
 		default:
-			ok = false
-		}
-		if !ok {
-			bsp.dropped++
+			bsp.countEnqueue(false)
+			return
 		}
 	}
+	if bsp.o.EarlyExport && len(bsp.queue) >= bsp.o.MaxExportBatchSize {
+		bsp.signalBatch()
+	}
+}
+
+// signalBatch makes the worker process the queue unless it was already
+// signaled. It yields the processor after signaling, so that the worker
+// gets to drain the queue even if spans are ended in a tight loop with
+// GOMAXPROCS=1.
+func (bsp *BatchSpanProcessor) signalBatch() {
+	select {
+	case bsp.batchCh <- struct{}{}:
+		runtime.Gosched()
+	default:
+	}
+}
+
+// countEnqueue counts a span that was queued, or dropped if ok is false,
+// and calls OnDropStateChange if that changes whether spans are dropped.
+func (bsp *BatchSpanProcessor) countEnqueue(ok bool) {
+	var dropping uint32
+	if ok {
+		atomic.AddUint64(&bsp.enqueued, 1)
+	} else {
+		atomic.AddUint64(&bsp.dropped, 1)
+		dropping = 1
+	}
+	if atomic.LoadUint32(&bsp.dropping) == dropping {
+		return
+	}
+	bsp.dropMu.Lock()
+	defer bsp.dropMu.Unlock()
+	if !atomic.CompareAndSwapUint32(&bsp.dropping, 1-dropping, dropping) {
+		return
+	}
+	if bsp.o.OnDropStateChange != nil {
+		bsp.o.OnDropStateChange(dropping == 1)
+	}
 }
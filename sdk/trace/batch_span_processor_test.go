@@ -16,6 +16,10 @@ package trace_test
 
 import (
 	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"reflect"
 	"sync"
 	"testing"
 	"time"
@@ -201,4 +205,622 @@ func getSpanContext() core.SpanContext {
 		SpanID:     sid,
 		TraceFlags: 0x1,
 	}
-}
\ No newline at end of file
+}
+
+func TestBatchSpanProcessorPersistentQueue(t *testing.T) {
+	sdktrace.SetErrorHandler(func(error) {})
+	defer sdktrace.SetErrorHandler(nil)
+
+	dir, err := ioutil.TempDir("", "bsp-queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The first processor never gets to export its spans before it
+	// "crashes": its exports fail, so the spans are never committed.
+	crashed := &errBatcher{err: errors.New("crashed")}
+	bsp, err := sdktrace.NewBatchSpanProcessorFromBatcher(crashed,
+		sdktrace.WithPersistentQueue(dir),
+		sdktrace.WithScheduleDelayMillis(time.Hour),
+	)
+	if err != nil {
+		t.Fatalf("Error creating new instance of BatchSpanProcessor, error: %v", err)
+	}
+	sc := getSpanContext()
+	for i := 0; i < 10; i++ {
+		sc.TraceID.High = uint64(i + 1)
+		bsp.OnEnd(&sdktrace.SpanData{SpanContext: sc, Name: "persisted"})
+	}
+	bsp.Shutdown()
+
+	te := testBatchExporter{}
+	bsp2, err := sdktrace.NewBatchSpanProcessor(&te,
+		sdktrace.WithPersistentQueue(dir),
+		sdktrace.WithMaxExportBatchSize(4),
+	)
+	if err != nil {
+		t.Fatalf("Error reopening persistent queue, error: %v", err)
+	}
+	bsp2.Shutdown()
+
+	if got, want := te.len(), 10; got != want {
+		t.Fatalf("number of exported span: got %d, want %d", got, want)
+	}
+	if got, want := te.getBatchCount(), 3; got != want {
+		t.Errorf("number batches: got %d, want %d", got, want)
+	}
+	if got := te.get(0); got.Name != "persisted" || got.SpanContext.TraceID.High != 1 {
+		t.Errorf("first exported span: got %+v", got)
+	}
+	if got := bsp.Stats().Exported; got != 0 {
+		t.Errorf("crashed processor exported %d spans", got)
+	}
+
+	// Exported spans are not delivered again.
+	te3 := testBatchExporter{}
+	bsp3, err := sdktrace.NewBatchSpanProcessor(&te3, sdktrace.WithPersistentQueue(dir))
+	if err != nil {
+		t.Fatalf("Error reopening persistent queue, error: %v", err)
+	}
+	bsp3.Shutdown()
+	if got := te3.len(); got != 0 {
+		t.Errorf("spans exported twice: got %d", got)
+	}
+}
+
+// flushingBatchExporter is a testBatchExporter that counts flushes and
+// blocks exports while block is open.
+type flushingBatchExporter struct {
+	testBatchExporter
+	block   chan struct{}
+	flushes int
+}
+
+func (t *flushingBatchExporter) ExportSpans(sds []*sdktrace.SpanData) {
+	if t.block != nil {
+		<-t.block
+	}
+	t.testBatchExporter.ExportSpans(sds)
+}
+
+func (t *flushingBatchExporter) ForceFlush(ctx context.Context) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.flushes++
+	return nil
+}
+
+func TestBatchSpanProcessorForceFlush(t *testing.T) {
+	te := &flushingBatchExporter{}
+	bsp, err := sdktrace.NewBatchSpanProcessor(te, sdktrace.WithScheduleDelayMillis(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := sdktrace.NewTracerProvider(
+		sdktrace.WithConfig(sdktrace.Config{DefaultSampler: sdktrace.AlwaysSample()}),
+		sdktrace.WithSpanProcessor(bsp),
+	)
+	for i := 0; i < 10; i++ {
+		_, span := p.Tracer().Start(context.Background(), "span")
+		span.End()
+	}
+
+	if err := p.ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := te.len(); got != 10 {
+		t.Errorf("got %d exported spans after ForceFlush, want 10", got)
+	}
+	if te.flushes != 1 {
+		t.Errorf("exporter flushed %d times, want once", te.flushes)
+	}
+
+	p.Shutdown()
+	if err := bsp.ForceFlush(context.Background()); err != nil {
+		t.Errorf("ForceFlush after Shutdown: %v", err)
+	}
+}
+
+func TestBatchSpanProcessorForceFlushDeadline(t *testing.T) {
+	te := &flushingBatchExporter{block: make(chan struct{})}
+	bsp, err := sdktrace.NewBatchSpanProcessor(te, sdktrace.WithScheduleDelayMillis(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := sdktrace.NewTracerProvider(
+		sdktrace.WithConfig(sdktrace.Config{DefaultSampler: sdktrace.AlwaysSample()}),
+		sdktrace.WithSpanProcessor(bsp),
+	)
+	defer p.Shutdown()
+	_, span := p.Tracer().Start(context.Background(), "span")
+	span.End()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := p.ForceFlush(ctx); err != context.DeadlineExceeded {
+		t.Errorf("ForceFlush with blocked exporter: got %v, want %v", err, context.DeadlineExceeded)
+	}
+	close(te.block)
+}
+
+// slowBatcher is a SpanBatcher that waits until the context is done.
+type slowBatcher struct{}
+
+func (slowBatcher) ExportSpans(ctx context.Context, sds []*sdktrace.SpanData) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestBatchSpanProcessorExportTimeout(t *testing.T) {
+	errs := make(chan error, 1)
+	sdktrace.SetErrorHandler(func(err error) { errs <- err })
+	defer sdktrace.SetErrorHandler(nil)
+
+	bsp, err := sdktrace.NewBatchSpanProcessorFromBatcher(slowBatcher{},
+		sdktrace.WithScheduleDelayMillis(time.Hour),
+		sdktrace.WithExportTimeout(10*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := sdktrace.NewTracerProvider(
+		sdktrace.WithConfig(sdktrace.Config{DefaultSampler: sdktrace.AlwaysSample()}),
+		sdktrace.WithSpanProcessor(bsp),
+	)
+	defer p.Shutdown()
+	_, span := p.Tracer().Start(context.Background(), "span")
+	span.End()
+
+	if err := p.ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-errs:
+		if want := "exporting batch of 1 spans: " + context.DeadlineExceeded.Error(); err.Error() != want {
+			t.Errorf("got error %q, want %q", err, want)
+		}
+	default:
+		t.Error("export timeout not reported")
+	}
+}
+
+func TestNewBatchSpanProcessorFromBatcherWithNilExporter(t *testing.T) {
+	if _, err := sdktrace.NewBatchSpanProcessorFromBatcher(nil); err == nil {
+		t.Errorf("Expected error while creating processor with nil exporter")
+	}
+}
+
+// errBatcher is a SpanBatcher that returns err.
+type errBatcher struct {
+	err error
+}
+
+func (b *errBatcher) ExportSpans(ctx context.Context, sds []*sdktrace.SpanData) error {
+	return b.err
+}
+
+func TestBatchSpanProcessorStats(t *testing.T) {
+	sdktrace.SetErrorHandler(func(error) {})
+	defer sdktrace.SetErrorHandler(nil)
+
+	var changes []bool
+	b := &errBatcher{}
+	bsp, err := sdktrace.NewBatchSpanProcessorFromBatcher(b,
+		sdktrace.WithScheduleDelayMillis(time.Hour),
+		sdktrace.WithMaxQueueSize(1),
+		sdktrace.WithDropNotifier(func(dropping bool) { changes = append(changes, dropping) }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bsp.Shutdown()
+	p := sdktrace.NewTracerProvider(
+		sdktrace.WithConfig(sdktrace.Config{DefaultSampler: sdktrace.AlwaysSample()}),
+		sdktrace.WithSpanProcessor(bsp),
+	)
+	tr := p.Tracer()
+	end := func(n int) {
+		for i := 0; i < n; i++ {
+			_, span := tr.Start(context.Background(), "span")
+			span.End()
+		}
+	}
+
+	end(3)
+	if err := bsp.ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	end(1)
+	b.err = errors.New("export failed")
+	if err := bsp.ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := sdktrace.BatchSpanProcessorStats{Enqueued: 2, Exported: 1, Dropped: 2, Failed: 1}
+	if got := bsp.Stats(); got != want {
+		t.Errorf("Stats: got %+v, want %+v", got, want)
+	}
+	if len(changes) != 2 || !changes[0] || changes[1] {
+		t.Errorf("drop state changes: got %v, want [true false]", changes)
+	}
+}
+
+func TestBatchSpanProcessorPersistentQueueWriteFailed(t *testing.T) {
+	var errs []error
+	sdktrace.SetErrorHandler(func(err error) { errs = append(errs, err) })
+	defer sdktrace.SetErrorHandler(nil)
+
+	dir, err := ioutil.TempDir("", "bsp-queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var changes []bool
+	bsp, err := sdktrace.NewBatchSpanProcessorFromBatcher(&errBatcher{},
+		sdktrace.WithPersistentQueue(dir),
+		sdktrace.WithPersistentQueueMaxBytes(1),
+		sdktrace.WithScheduleDelayMillis(time.Hour),
+		sdktrace.WithDropNotifier(func(dropping bool) { changes = append(changes, dropping) }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sd := &sdktrace.SpanData{SpanContext: getSpanContext(), Name: "persisted"}
+	bsp.OnEnd(sd)
+	bsp.Shutdown()
+	// The disk queue is closed now, so writing to it fails.
+	bsp.OnEnd(sd)
+
+	want := sdktrace.BatchSpanProcessorStats{Dropped: 1, WriteFailed: 1}
+	if got := bsp.Stats(); got != want {
+		t.Errorf("Stats: got %+v, want %+v", got, want)
+	}
+	if len(changes) != 1 || !changes[0] {
+		t.Errorf("drop state changes: got %v, want [true]", changes)
+	}
+	if len(errs) != 1 {
+		t.Errorf("errors: got %v, want one write error", errs)
+	}
+}
+
+// blockingBatcher is a SpanBatcher that blocks until release is closed and
+// records the highest number of concurrent exports.
+type blockingBatcher struct {
+	release chan struct{}
+
+	mu       sync.Mutex
+	active   int
+	max      int
+	exported int
+}
+
+func (b *blockingBatcher) ExportSpans(ctx context.Context, sds []*sdktrace.SpanData) error {
+	b.mu.Lock()
+	b.active++
+	if b.active > b.max {
+		b.max = b.active
+	}
+	b.mu.Unlock()
+	<-b.release
+	b.mu.Lock()
+	b.active--
+	b.exported += len(sds)
+	b.mu.Unlock()
+	return nil
+}
+
+func newBatchTestProvider(bsp *sdktrace.BatchSpanProcessor) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithConfig(sdktrace.Config{DefaultSampler: sdktrace.AlwaysSample()}),
+		sdktrace.WithSpanProcessor(bsp),
+	)
+}
+
+func endSpans(tr apitrace.Tracer, n int) {
+	for i := 0; i < n; i++ {
+		_, span := tr.Start(context.Background(), "span")
+		span.End()
+	}
+}
+
+func TestBatchSpanProcessorExportsFullBatch(t *testing.T) {
+	te := &testBatchExporter{}
+	bsp, err := sdktrace.NewBatchSpanProcessor(te,
+		sdktrace.WithScheduleDelayMillis(time.Hour),
+		sdktrace.WithMaxExportBatchSize(10),
+		sdktrace.WithEarlyExport(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bsp.Shutdown()
+	endSpans(newBatchTestProvider(bsp).Tracer(), 10)
+
+	for deadline := time.Now().Add(time.Second); te.len() < 10; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("full batch not exported before schedule delay: got %d spans", te.len())
+		}
+	}
+}
+
+func TestBatchSpanProcessorExportsFullQueue(t *testing.T) {
+	te := &testBatchExporter{}
+	// The batch size is limited to the queue size, so a full queue is a
+	// full batch.
+	bsp, err := sdktrace.NewBatchSpanProcessor(te,
+		sdktrace.WithScheduleDelayMillis(time.Hour),
+		sdktrace.WithMaxQueueSize(5),
+		sdktrace.WithMaxExportBatchSize(10),
+		sdktrace.WithEarlyExport(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bsp.Shutdown()
+	endSpans(newBatchTestProvider(bsp).Tracer(), 5)
+
+	for deadline := time.Now().Add(time.Second); te.len() < 5; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("full queue not exported before schedule delay: got %d spans", te.len())
+		}
+	}
+}
+
+func TestBatchSpanProcessorMaxConcurrentExports(t *testing.T) {
+	b := &blockingBatcher{release: make(chan struct{})}
+	bsp, err := sdktrace.NewBatchSpanProcessorFromBatcher(b,
+		sdktrace.WithScheduleDelayMillis(time.Hour),
+		sdktrace.WithMaxExportBatchSize(1),
+		sdktrace.WithMaxConcurrentExports(2),
+		sdktrace.WithBlocking(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	endSpans(newBatchTestProvider(bsp).Tracer(), 5)
+
+	time.AfterFunc(50*time.Millisecond, func() { close(b.release) })
+	bsp.Shutdown()
+
+	if b.exported != 5 {
+		t.Errorf("exported %d spans, want 5", b.exported)
+	}
+	if b.max != 2 {
+		t.Errorf("got at most %d concurrent exports, want 2", b.max)
+	}
+}
+
+func TestBatchSpanProcessorShutdownContext(t *testing.T) {
+	b := &blockingBatcher{release: make(chan struct{})}
+	bsp, err := sdktrace.NewBatchSpanProcessorFromBatcher(b,
+		sdktrace.WithScheduleDelayMillis(time.Hour),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	endSpans(newBatchTestProvider(bsp).Tracer(), 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := bsp.ShutdownContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("ShutdownContext: got %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	close(b.release)
+	if err := bsp.ShutdownContext(context.Background()); err != nil {
+		t.Errorf("ShutdownContext after export: got %v", err)
+	}
+	if b.exported != 1 {
+		t.Errorf("exported %d spans, want 1", b.exported)
+	}
+}
+
+// ctxBatcher is a SpanBatcher that blocks until the context of the export
+// is done.
+type ctxBatcher struct{}
+
+func (ctxBatcher) ExportSpans(ctx context.Context, sds []*sdktrace.SpanData) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestBatchSpanProcessorShutdownContextCancelsExport(t *testing.T) {
+	sdktrace.SetErrorHandler(func(error) {})
+	defer sdktrace.SetErrorHandler(nil)
+
+	bsp, err := sdktrace.NewBatchSpanProcessorFromBatcher(ctxBatcher{},
+		sdktrace.WithScheduleDelayMillis(time.Hour),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	endSpans(newBatchTestProvider(bsp).Tracer(), 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := bsp.ShutdownContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("ShutdownContext: got %v, want %v", err, context.DeadlineExceeded)
+	}
+	// The export was canceled with the shutdown context rather than after
+	// the export timeout.
+	wait, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := bsp.ShutdownContext(wait); err != nil {
+		t.Errorf("ShutdownContext after deadline: got %v", err)
+	}
+	if got := bsp.Stats().Failed; got != 1 {
+		t.Errorf("failed spans: got %d, want 1", got)
+	}
+}
+
+func TestBatchSpanProcessorPersistentQueueMaxConcurrentExports(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bsp-queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	b := &blockingBatcher{release: make(chan struct{})}
+	bsp, err := sdktrace.NewBatchSpanProcessorFromBatcher(b,
+		sdktrace.WithPersistentQueue(dir),
+		sdktrace.WithScheduleDelayMillis(time.Hour),
+		sdktrace.WithMaxExportBatchSize(1),
+		sdktrace.WithMaxConcurrentExports(2),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := getSpanContext()
+	for i := 0; i < 5; i++ {
+		bsp.OnEnd(&sdktrace.SpanData{SpanContext: sc, Name: "persisted"})
+	}
+
+	time.AfterFunc(50*time.Millisecond, func() { close(b.release) })
+	bsp.Shutdown()
+
+	if b.exported != 5 {
+		t.Errorf("exported %d spans, want 5", b.exported)
+	}
+	if b.max != 2 {
+		t.Errorf("got at most %d concurrent exports, want 2", b.max)
+	}
+}
+
+func TestBatchSpanProcessorPersistentQueueExportsFullBatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bsp-queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	te := &testBatchExporter{}
+	bsp, err := sdktrace.NewBatchSpanProcessor(te,
+		sdktrace.WithPersistentQueue(dir),
+		sdktrace.WithScheduleDelayMillis(time.Hour),
+		sdktrace.WithMaxExportBatchSize(4),
+		sdktrace.WithEarlyExport(),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bsp.Shutdown()
+	sc := getSpanContext()
+	for i := 0; i < 4; i++ {
+		bsp.OnEnd(&sdktrace.SpanData{SpanContext: sc, Name: "persisted"})
+	}
+
+	for deadline := time.Now().Add(time.Second); te.len() < 4; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("full batch not exported before schedule delay: got %d spans", te.len())
+		}
+	}
+}
+
+// sleepBatcher is a SpanBatcher that takes d to export a batch.
+type sleepBatcher struct {
+	d time.Duration
+}
+
+func (b sleepBatcher) ExportSpans(ctx context.Context, sds []*sdktrace.SpanData) error {
+	time.Sleep(b.d)
+	return nil
+}
+
+// BenchmarkBatchSpanProcessorBurst ends bursts of spans that exceed the
+// queue size, with a pause between the bursts, and reports the number of
+// dropped spans per burst, with the queue processed only by the ticker and
+// with early export of full batches.
+func BenchmarkBatchSpanProcessorBurst(b *testing.B) {
+	for _, bc := range []struct {
+		name    string
+		early   bool
+		workers int
+	}{
+		{"ticker only", false, 1},
+		{"early export", true, 1},
+		{"early export with 4 workers", true, 4},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			opts := []sdktrace.BatchSpanProcessorOption{
+				sdktrace.WithScheduleDelayMillis(10 * time.Millisecond),
+				sdktrace.WithMaxQueueSize(512),
+				sdktrace.WithMaxExportBatchSize(128),
+				sdktrace.WithMaxConcurrentExports(bc.workers),
+			}
+			if bc.early {
+				opts = append(opts, sdktrace.WithEarlyExport())
+			}
+			bsp, err := sdktrace.NewBatchSpanProcessorFromBatcher(sleepBatcher{d: 100 * time.Microsecond}, opts...)
+			if err != nil {
+				b.Fatal(err)
+			}
+			tr := newBatchTestProvider(bsp).Tracer()
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				endSpans(tr, 1024)
+				b.StopTimer()
+				time.Sleep(20 * time.Millisecond)
+				b.StartTimer()
+			}
+			b.StopTimer()
+			bsp.Shutdown()
+			b.ReportMetric(float64(bsp.Stats().Dropped)/float64(b.N), "dropped/op")
+		})
+	}
+}
+
+// failOnceBatcher is a SpanBatcher that fails the first export and records
+// the names of the spans of the following ones.
+type failOnceBatcher struct {
+	failed bool
+	names  []string
+}
+
+func (b *failOnceBatcher) ExportSpans(ctx context.Context, sds []*sdktrace.SpanData) error {
+	if !b.failed {
+		b.failed = true
+		return errors.New("export failed")
+	}
+	for _, sd := range sds {
+		b.names = append(b.names, sd.Name)
+	}
+	return nil
+}
+
+func TestBatchSpanProcessorPersistentQueueRetry(t *testing.T) {
+	sdktrace.SetErrorHandler(func(error) {})
+	defer sdktrace.SetErrorHandler(nil)
+
+	dir, err := ioutil.TempDir("", "bsp-queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	b := &failOnceBatcher{}
+	bsp, err := sdktrace.NewBatchSpanProcessorFromBatcher(b,
+		sdktrace.WithPersistentQueue(dir),
+		sdktrace.WithScheduleDelayMillis(time.Hour),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sc := getSpanContext()
+	for _, name := range []string{"a", "b"} {
+		bsp.OnEnd(&sdktrace.SpanData{SpanContext: sc, Name: name})
+	}
+	if err := bsp.ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !b.failed || len(b.names) != 0 {
+		t.Fatalf("first export: failed %v, exported %v", b.failed, b.names)
+	}
+	bsp.Shutdown()
+
+	if want := []string{"a", "b"}; !reflect.DeepEqual(b.names, want) {
+		t.Errorf("spans exported after failure: got %v, want %v", b.names, want)
+	}
+}
@@ -15,18 +15,17 @@
 package trace
 
 import (
-	"sync"
-
-	"go.opentelemetry.io/sdk/trace/internal"
+	"go.opentelemetry.io/api/core"
+	"go.opentelemetry.io/sdk/resource"
 )
 
-// Config represents the global tracing configuration.
+// Config represents the tracing configuration of a TracerProvider.
 type Config struct {
 	// DefaultSampler is the default sampler used when creating new spans.
 	DefaultSampler Sampler
 
-	// IDGenerator is for internal use only.
-	IDGenerator internal.IDGenerator
+	// IDGenerator generates the trace and span IDs of new spans.
+	IDGenerator IDGenerator
 
 	// MaxEventsPerSpan is max number of message events per span
 	MaxEventsPerSpan int
@@ -36,9 +35,27 @@ type Config struct {
 
 	// MaxLinksPerSpan is max number of links per span
 	MaxLinksPerSpan int
-}
 
-var configWriteMu sync.Mutex
+	// MaxAttributesPerEvent is max number of attributes per message event
+	MaxAttributesPerEvent int
+
+	// MaxAttributesPerLink is max number of attributes per link
+	MaxAttributesPerLink int
+
+	// MaxAttributeValueLength is the max length in bytes of string and
+	// byte values of the attributes of spans, events and links. Longer
+	// values are truncated. Zero means no limit.
+	MaxAttributeValueLength int
+
+	// MaxAttributeValueLengthPerKey overrides MaxAttributeValueLength for
+	// the attributes with the given keys. A limit of zero or less disables
+	// truncation for the key.
+	MaxAttributeValueLengthPerKey map[core.Key]int
+
+	// Resource describes the entity producing the spans. It is attached
+	// to every SpanData.
+	Resource *resource.Resource
+}
 
 const (
 	// DefaultMaxEventsPerSpan is default max number of message events per span
@@ -49,29 +66,18 @@ const (
 
 	// DefaultMaxLinksPerSpan is default max number of links per span
 	DefaultMaxLinksPerSpan = 32
+
+	// DefaultMaxAttributesPerEvent is default max number of attributes per message event
+	DefaultMaxAttributesPerEvent = 32
+
+	// DefaultMaxAttributesPerLink is default max number of attributes per link
+	DefaultMaxAttributesPerLink = 32
 )
 
-// ApplyConfig applies changes to the global tracing configuration.
+// ApplyConfig applies changes to the configuration of the default
+// TracerProvider.
 //
 // Fields not provided in the given config are going to be preserved.
 func ApplyConfig(cfg Config) {
-	configWriteMu.Lock()
-	defer configWriteMu.Unlock()
-	c := *config.Load().(*Config)
-	if cfg.DefaultSampler != nil {
-		c.DefaultSampler = cfg.DefaultSampler
-	}
-	if cfg.IDGenerator != nil {
-		c.IDGenerator = cfg.IDGenerator
-	}
-	if cfg.MaxEventsPerSpan > 0 {
-		c.MaxEventsPerSpan = cfg.MaxEventsPerSpan
-	}
-	if cfg.MaxAttributesPerSpan > 0 {
-		c.MaxAttributesPerSpan = cfg.MaxAttributesPerSpan
-	}
-	if cfg.MaxLinksPerSpan > 0 {
-		c.MaxLinksPerSpan = cfg.MaxLinksPerSpan
-	}
-	config.Store(&c)
+	defaultProvider.ApplyConfig(cfg)
 }
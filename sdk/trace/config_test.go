@@ -32,7 +32,7 @@ func TestApplyConfig(t *testing.T) {
 			MaxEventsPerSpan:     -3,
 			MaxLinksPerSpan:      5,
 		}}
-	cfg := config.Load().(*Config)
+	cfg := defaultProvider.getConfig()
 	wantCfgs := []Config{
 		{
 			DefaultSampler:       cfg.DefaultSampler,
@@ -58,7 +58,7 @@ func TestApplyConfig(t *testing.T) {
 
 	for i, newCfg := range testCfgs {
 		ApplyConfig(newCfg)
-		gotCfg := config.Load().(*Config)
+		gotCfg := defaultProvider.getConfig()
 		wantCfg := wantCfgs[i]
 
 		if got, want := reflect.ValueOf(gotCfg.DefaultSampler).Pointer(), reflect.ValueOf(wantCfg.DefaultSampler).Pointer(); got != want {
@@ -17,5 +17,11 @@ Package trace contains support for OpenTelemetry distributed tracing.
 
 The following assumes a basic familiarity with OpenTelemetry concepts.
 See http://opentelemetry.io
+
+The Sampler of a span decides whether it is sampled, only recorded, or
+dropped. Recorded spans are passed to the span processors, so that they
+can compute metrics from all spans, but SimpleSpanProcessor and
+BatchSpanProcessor only export sampled spans. Exporters registered with
+RegisterExporter get sampled spans only as well.
 */
 package trace // import "go.opentelemetry.io/sdk/trace"
@@ -0,0 +1,40 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"log"
+	"sync/atomic"
+)
+
+// ErrorHandler handles errors that cannot be returned to a caller, such as
+// the export failures of span processors.
+type ErrorHandler func(err error)
+
+var errorHandler atomic.Value // holds ErrorHandler
+
+// SetErrorHandler sets the handler of the errors of all span processors.
+// By default, or if h is nil, errors are logged with log.Printf.
+func SetErrorHandler(h ErrorHandler) {
+	errorHandler.Store(h)
+}
+
+func handleError(err error) {
+	if h, _ := errorHandler.Load().(ErrorHandler); h != nil {
+		h(err)
+		return
+	}
+	log.Printf("Error when exporting spans: %v", err)
+}
@@ -15,14 +15,14 @@
 package trace
 
 import (
-	"sync"
-	"sync/atomic"
+	"context"
 	"time"
 
 	"google.golang.org/grpc/codes"
 
 	"go.opentelemetry.io/api/core"
 	apitrace "go.opentelemetry.io/api/trace"
+	"go.opentelemetry.io/sdk/resource"
 )
 
 // BatchExporter is a type for functions that receive sampled trace spans.
@@ -46,45 +46,124 @@ type Exporter interface {
 	ExportSpan(s *SpanData)
 }
 
-type exportersMap map[Exporter]struct{}
+// SpanSyncer is a context-aware Exporter that reports failures. The
+// ExportSpan method is called synchronously when a span ends and should
+// return when the context is done.
+//
+// The SpanData should not be modified, but a pointer to it can be kept.
+type SpanSyncer interface {
+	ExportSpan(ctx context.Context, sd *SpanData) error
+}
 
-var (
-	exporterMu sync.Mutex
-	exporters  atomic.Value
-)
+// SpanBatcher is a context-aware BatchExporter that reports failures. The
+// ExportSpans method is called asynchronously and should return when the
+// context is done, as the batch span processor enforces a timeout through
+// the context.
+//
+// The SpanData should not be modified.
+type SpanBatcher interface {
+	ExportSpans(ctx context.Context, sds []*SpanData) error
+}
+
+// SyncerFromExporter adapts an Exporter to a SpanSyncer that never fails.
+// The returned SpanSyncer flushes e if it implements Flusher.
+func SyncerFromExporter(e Exporter) SpanSyncer {
+	return exporterSyncer{e}
+}
+
+// BatcherFromBatchExporter adapts a BatchExporter to a SpanBatcher that
+// never fails. The returned SpanBatcher flushes e if it implements Flusher.
+func BatcherFromBatchExporter(e BatchExporter) SpanBatcher {
+	return batchExporterBatcher{e}
+}
+
+// BatcherFromSyncer adapts a SpanSyncer to a SpanBatcher that exports the
+// spans of a batch one by one until the context is done. It returns the
+// first error. The returned SpanBatcher flushes s if it implements Flusher.
+func BatcherFromSyncer(s SpanSyncer) SpanBatcher {
+	return syncerBatcher{s}
+}
+
+type exporterSyncer struct {
+	e Exporter
+}
+
+func (s exporterSyncer) ExportSpan(ctx context.Context, sd *SpanData) error {
+	s.e.ExportSpan(sd)
+	return nil
+}
+
+func (s exporterSyncer) ForceFlush(ctx context.Context) error {
+	return flushExporter(ctx, s.e)
+}
+
+type batchExporterBatcher struct {
+	e BatchExporter
+}
+
+func (b batchExporterBatcher) ExportSpans(ctx context.Context, sds []*SpanData) error {
+	b.e.ExportSpans(sds)
+	return nil
+}
+
+func (b batchExporterBatcher) ForceFlush(ctx context.Context) error {
+	return flushExporter(ctx, b.e)
+}
 
-// RegisterExporter adds to the list of Exporters that will receive sampled
-// trace spans.
+type syncerBatcher struct {
+	s SpanSyncer
+}
+
+func (b syncerBatcher) ExportSpans(ctx context.Context, sds []*SpanData) error {
+	var firstErr error
+	for _, sd := range sds {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := b.s.ExportSpan(ctx, sd); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (b syncerBatcher) ForceFlush(ctx context.Context) error {
+	return flushExporter(ctx, b.s)
+}
+
+// Flusher is implemented by exporters that buffer spans. Span processors
+// flush their exporter when they are flushed.
+//
+// ForceFlush should send all spans exported so far before it returns, or
+// return the error of the context when it is done first.
+type Flusher interface {
+	ForceFlush(ctx context.Context) error
+}
+
+// flushExporter flushes the exporter if it implements Flusher.
+func flushExporter(ctx context.Context, e interface{}) error {
+	if f, ok := e.(Flusher); ok {
+		return f.ForceFlush(ctx)
+	}
+	return nil
+}
+
+type exportersMap map[Exporter]struct{}
+
+// RegisterExporter adds to the list of Exporters of the default
+// TracerProvider that will receive sampled trace spans.
 //
 // Binaries can register exporters, libraries shouldn't register exporters.
 // TODO(rghetia) : Remove it.
 func RegisterExporter(e Exporter) {
-	exporterMu.Lock()
-	defer exporterMu.Unlock()
-	new := make(exportersMap)
-	if old, ok := exporters.Load().(exportersMap); ok {
-		for k, v := range old {
-			new[k] = v
-		}
-	}
-	new[e] = struct{}{}
-	exporters.Store(new)
+	defaultProvider.RegisterExporter(e)
 }
 
-// UnregisterExporter removes from the list of Exporters the Exporter that was
-// registered with the given name.
+// UnregisterExporter removes from the list of Exporters of the default
+// TracerProvider the Exporter that was registered with the given name.
 // TODO(rghetia) : Remove it.
 func UnregisterExporter(e Exporter) {
-	exporterMu.Lock()
-	defer exporterMu.Unlock()
-	new := make(exportersMap)
-	if old, ok := exporters.Load().(exportersMap); ok {
-		for k, v := range old {
-			new[k] = v
-		}
-	}
-	delete(new, e)
-	exporters.Store(new)
+	defaultProvider.UnregisterExporter(e)
 }
 
 // SpanData contains all the information collected by a span.
@@ -107,8 +186,27 @@ type SpanData struct {
 	DroppedMessageEventCount int
 	DroppedLinkCount         int
 
+	// DroppedEventAttributeCount and DroppedLinkAttributeCount hold the
+	// number of attributes dropped from events and links that exceeded
+	// the limit of attributes per event and per link.
+	DroppedEventAttributeCount int
+	DroppedLinkAttributeCount  int
+
+	// TruncatedAttributeValueCount holds the number of attribute values of
+	// the span, its events and its links that were truncated.
+	TruncatedAttributeValueCount int
+
 	// ChildSpanCount holds the number of child span created for this span.
 	ChildSpanCount int
+
+	// TracerName and TracerVersion identify the instrumentation library
+	// whose tracer started the span. They are empty for spans started by
+	// the anonymous tracer of a TracerProvider.
+	TracerName    string
+	TracerVersion string
+
+	// Resource describes the entity that produced the span.
+	Resource *resource.Resource
 }
 
 // Event is used to describe an Event with a message string and set of
@@ -0,0 +1,86 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/sdk/trace"
+)
+
+// failingSyncer is a SpanSyncer that fails for spans with a given name.
+type failingSyncer struct {
+	fail     string
+	exported []string
+	flushed  bool
+}
+
+func (s *failingSyncer) ExportSpan(ctx context.Context, sd *sdktrace.SpanData) error {
+	if sd.Name == s.fail {
+		return errors.New("failed to export " + sd.Name)
+	}
+	s.exported = append(s.exported, sd.Name)
+	return nil
+}
+
+func (s *failingSyncer) ForceFlush(ctx context.Context) error {
+	s.flushed = true
+	return nil
+}
+
+func TestBatcherFromSyncer(t *testing.T) {
+	s := &failingSyncer{fail: "b"}
+	b := sdktrace.BatcherFromSyncer(s)
+	sds := []*sdktrace.SpanData{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+
+	if err := b.ExportSpans(context.Background(), sds); err == nil || err.Error() != "failed to export b" {
+		t.Errorf("ExportSpans: got error %v, want failed to export b", err)
+	}
+	if len(s.exported) != 2 {
+		t.Errorf("exported %v, want a and c", s.exported)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.exported = nil
+	if err := b.ExportSpans(ctx, sds); err != context.Canceled {
+		t.Errorf("ExportSpans with canceled context: got %v, want %v", err, context.Canceled)
+	}
+	if len(s.exported) != 0 {
+		t.Errorf("exported %v with canceled context", s.exported)
+	}
+
+	if err := b.(sdktrace.Flusher).ForceFlush(context.Background()); err != nil || !s.flushed {
+		t.Errorf("ForceFlush not passed to syncer: %v", err)
+	}
+}
+
+func TestSyncerFromExporter(t *testing.T) {
+	te := &flushingExporter{}
+	s := sdktrace.SyncerFromExporter(te)
+	if err := s.ExportSpan(context.Background(), &sdktrace.SpanData{Name: "span"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(te.spans) != 1 {
+		t.Errorf("got %d exported spans, want 1", len(te.spans))
+	}
+
+	te.err = errors.New("flush failed")
+	if err := s.(sdktrace.Flusher).ForceFlush(context.Background()); err != te.err {
+		t.Errorf("ForceFlush: got %v, want %v", err, te.err)
+	}
+}
@@ -15,14 +15,28 @@
 package trace
 
 import (
+	crand "crypto/rand"
+	"encoding/binary"
 	"math/rand"
 	"sync"
 	"sync/atomic"
 
 	"go.opentelemetry.io/api/core"
-	"go.opentelemetry.io/sdk/trace/internal"
 )
 
+// IDGenerator generates trace and span IDs. Implementations must be safe
+// for concurrent use and must never return a zero ID. The package
+// go.opentelemetry.io/sdk/trace/idgen provides common generators and
+// go.opentelemetry.io/sdk/trace/idgen/idgentest a test suite checking the
+// contract.
+type IDGenerator interface {
+	// NewTraceID returns a new trace ID.
+	NewTraceID() core.TraceID
+
+	// NewSpanID returns a new span ID.
+	NewSpanID() uint64
+}
+
 type defaultIDGenerator struct {
 	sync.Mutex
 
@@ -41,7 +55,22 @@ type defaultIDGenerator struct {
 	traceIDRand *rand.Rand
 }
 
-var _ internal.IDGenerator = &defaultIDGenerator{}
+var _ IDGenerator = &defaultIDGenerator{}
+
+// newDefaultIDGenerator returns a defaultIDGenerator seeded from a
+// cryptographically secure source.
+func newDefaultIDGenerator() *defaultIDGenerator {
+	gen := &defaultIDGenerator{}
+	var rngSeed int64
+	for _, p := range []interface{}{
+		&rngSeed, &gen.traceIDAdd, &gen.nextSpanID, &gen.spanIDInc,
+	} {
+		_ = binary.Read(crand.Reader, binary.LittleEndian, p)
+	}
+	gen.traceIDRand = rand.New(rand.NewSource(rngSeed))
+	gen.spanIDInc |= 1
+	return gen
+}
 
 // NewSpanID returns a non-zero span ID from a randomly-chosen sequence.
 func (gen *defaultIDGenerator) NewSpanID() uint64 {
@@ -12,13 +12,14 @@
 // See the License for the specific language governing permissions and
 // limitations under the License.
 
-// Package internal provides trace internals.
-package internal
+package trace
 
-import "go.opentelemetry.io/api/core"
+import (
+	"testing"
 
-// IDGenerator allows custom generators for TraceId and SpanId.
-type IDGenerator interface {
-	NewTraceID() core.TraceID
-	NewSpanID() uint64
+	"go.opentelemetry.io/sdk/trace/idgen/idgentest"
+)
+
+func TestDefaultIDGenerator(t *testing.T) {
+	idgentest.Run(t, func() idgentest.Generator { return newDefaultIDGenerator() })
 }
@@ -0,0 +1,127 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package idgen provides trace and span ID generators implementing
+// trace.IDGenerator, to be set in trace.Config.
+package idgen // import "go.opentelemetry.io/sdk/trace/idgen"
+
+import (
+	crand "crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/api/core"
+	"go.opentelemetry.io/sdk/trace"
+)
+
+var (
+	_ trace.IDGenerator = Random{}
+	_ trace.IDGenerator = (*XRay)(nil)
+	_ trace.IDGenerator = (*Sequential)(nil)
+)
+
+// Random generates IDs from a cryptographically secure random source. It
+// is slower than the default generator of the SDK but its IDs cannot be
+// predicted from previous IDs.
+type Random struct{}
+
+// NewTraceID returns a random non-zero trace ID.
+func (Random) NewTraceID() core.TraceID {
+	var b [16]byte
+	for {
+		readRandom(b[:])
+		tid := core.TraceID{
+			High: binary.BigEndian.Uint64(b[:8]),
+			Low:  binary.BigEndian.Uint64(b[8:]),
+		}
+		if tid.High != 0 || tid.Low != 0 {
+			return tid
+		}
+	}
+}
+
+// NewSpanID returns a random non-zero span ID.
+func (Random) NewSpanID() uint64 {
+	var b [8]byte
+	for {
+		readRandom(b[:])
+		if id := binary.BigEndian.Uint64(b[:]); id != 0 {
+			return id
+		}
+	}
+}
+
+// readRandom fills b from crypto/rand. A failing system random source
+// leaves no safe way to generate IDs, so it panics.
+func readRandom(b []byte) {
+	if _, err := crand.Read(b); err != nil {
+		panic(fmt.Sprintf("idgen: reading random bytes: %v", err))
+	}
+}
+
+// XRay generates trace IDs compatible with AWS X-Ray: the first 32 bits
+// are the start time in Unix seconds, the remaining 96 bits are random.
+// Span IDs are random. The zero value is ready to use.
+type XRay struct {
+	// now returns the start time of a trace. If nil, time.Now is used.
+	now func() time.Time
+}
+
+// NewXRay returns an X-Ray compatible generator.
+func NewXRay() *XRay {
+	return &XRay{now: time.Now}
+}
+
+// NewTraceID returns a trace ID prefixed with the current time.
+func (g *XRay) NewTraceID() core.TraceID {
+	now := g.now
+	if now == nil {
+		now = time.Now
+	}
+	tid := Random{}.NewTraceID()
+	tid.High = uint64(now().Unix())<<32 | tid.High&0xffffffff
+	return tid
+}
+
+// NewSpanID returns a random non-zero span ID.
+func (g *XRay) NewSpanID() uint64 {
+	return Random{}.NewSpanID()
+}
+
+// Sequential generates deterministic IDs counting up from 1, which makes
+// the output of tests reproducible. It must not be used in production.
+type Sequential struct {
+	// Please keep these as the first fields so that they are aligned on
+	// addresses divisible by 8 for atomic access on 32-bit machines.
+	traceID uint64
+	spanID  uint64
+}
+
+// NewSequential returns a sequential generator whose first trace and span
+// IDs are 1.
+func NewSequential() *Sequential {
+	return &Sequential{}
+}
+
+// NewTraceID returns the next trace ID. Its upper 64 bits are zero.
+func (g *Sequential) NewTraceID() core.TraceID {
+	return core.TraceID{Low: atomic.AddUint64(&g.traceID, 1)}
+}
+
+// NewSpanID returns the next span ID.
+func (g *Sequential) NewSpanID() uint64 {
+	return atomic.AddUint64(&g.spanID, 1)
+}
@@ -0,0 +1,63 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package idgen
+
+import (
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/api/core"
+	"go.opentelemetry.io/sdk/trace/idgen/idgentest"
+)
+
+func TestRandom(t *testing.T) {
+	idgentest.Run(t, func() idgentest.Generator { return Random{} })
+}
+
+func TestXRay(t *testing.T) {
+	idgentest.Run(t, func() idgentest.Generator { return NewXRay() })
+
+	now := time.Unix(1569931200, 0)
+	g := &XRay{now: func() time.Time { return now }}
+	for i := 0; i < 10; i++ {
+		if got, want := g.NewTraceID().High>>32, uint64(now.Unix()); got != want {
+			t.Fatalf("time prefix of trace ID: got %d, want %d", got, want)
+		}
+	}
+}
+
+func TestXRayZeroValue(t *testing.T) {
+	idgentest.Run(t, func() idgentest.Generator { return &XRay{} })
+
+	before := uint64(time.Now().Unix())
+	prefix := (&XRay{}).NewTraceID().High >> 32
+	if after := uint64(time.Now().Unix()); prefix < before || prefix > after {
+		t.Errorf("time prefix of trace ID: got %d, want between %d and %d", prefix, before, after)
+	}
+}
+
+func TestSequential(t *testing.T) {
+	idgentest.Run(t, func() idgentest.Generator { return NewSequential() })
+
+	g := NewSequential()
+	for i := uint64(1); i <= 3; i++ {
+		if got, want := g.NewTraceID(), (core.TraceID{Low: i}); got != want {
+			t.Errorf("trace ID %d: got %v, want %v", i, got, want)
+		}
+		if got := g.NewSpanID(); got != i {
+			t.Errorf("span ID %d: got %d, want %d", i, got, i)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package idgentest provides a test suite for implementations of
+// trace.IDGenerator.
+package idgentest // import "go.opentelemetry.io/sdk/trace/idgen/idgentest"
+
+import (
+	"sync"
+	"testing"
+
+	"go.opentelemetry.io/api/core"
+)
+
+// Generator is the interface of trace.IDGenerator. It is repeated here so
+// that the trace package can run the suite against its own generator.
+type Generator interface {
+	NewTraceID() core.TraceID
+	NewSpanID() uint64
+}
+
+// N is the number of IDs generated by every test of the suite.
+const N = 10000
+
+// Run checks that the generators returned by newGenerator fulfill the
+// contract of trace.IDGenerator: IDs are never zero, they do not repeat
+// and the generator is safe for concurrent use. Every test uses a new
+// generator.
+func Run(t *testing.T, newGenerator func() Generator) {
+	t.Run("NonZero", func(t *testing.T) {
+		g := newGenerator()
+		for i := 0; i < N; i++ {
+			if tid := g.NewTraceID(); tid.High == 0 && tid.Low == 0 {
+				t.Fatalf("NewTraceID returned a zero trace ID after %d IDs", i)
+			}
+			if sid := g.NewSpanID(); sid == 0 {
+				t.Fatalf("NewSpanID returned a zero span ID after %d IDs", i)
+			}
+		}
+	})
+	t.Run("Unique", func(t *testing.T) {
+		g := newGenerator()
+		checkUnique(t, generate(g, N))
+	})
+	t.Run("Concurrent", func(t *testing.T) {
+		const goroutines = 8
+		g := newGenerator()
+		results := make([]ids, goroutines)
+		var wg sync.WaitGroup
+		for i := range results {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				results[i] = generate(g, N/goroutines)
+			}(i)
+		}
+		wg.Wait()
+		var all ids
+		for _, r := range results {
+			all.traceIDs = append(all.traceIDs, r.traceIDs...)
+			all.spanIDs = append(all.spanIDs, r.spanIDs...)
+		}
+		checkUnique(t, all)
+	})
+}
+
+type ids struct {
+	traceIDs []core.TraceID
+	spanIDs  []uint64
+}
+
+func generate(g Generator, n int) ids {
+	r := ids{
+		traceIDs: make([]core.TraceID, n),
+		spanIDs:  make([]uint64, n),
+	}
+	for i := 0; i < n; i++ {
+		r.traceIDs[i] = g.NewTraceID()
+		r.spanIDs[i] = g.NewSpanID()
+	}
+	return r
+}
+
+func checkUnique(t *testing.T, r ids) {
+	t.Helper()
+	traceIDs := make(map[core.TraceID]bool, len(r.traceIDs))
+	for _, tid := range r.traceIDs {
+		if traceIDs[tid] {
+			t.Fatalf("NewTraceID returned %.16x%.16x twice", tid.High, tid.Low)
+		}
+		traceIDs[tid] = true
+	}
+	spanIDs := make(map[uint64]bool, len(r.spanIDs))
+	for _, sid := range r.spanIDs {
+		if spanIDs[sid] {
+			t.Fatalf("NewSpanID returned %.16x twice", sid)
+		}
+		spanIDs[sid] = true
+	}
+}
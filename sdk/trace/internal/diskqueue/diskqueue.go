@@ -0,0 +1,481 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package diskqueue provides a persistent FIFO queue of byte records.
+//
+// Records are appended to segment files in a directory. Every record is
+// framed with its length and a CRC-32 checksum of its payload, so that
+// torn writes and corrupted data are detected when the queue is reopened.
+// The read position is persisted separately and only advanced by Commit,
+// which gives at-least-once delivery across process restarts.
+package diskqueue // import "go.opentelemetry.io/sdk/trace/internal/diskqueue"
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	headerSize     = 8
+	segmentSuffix  = ".seg"
+	cursorFileName = "cursor"
+	lockFileName   = "lock"
+
+	defaultMaxSegmentSize = 8 * 1024 * 1024
+	defaultMaxSize        = 256 * 1024 * 1024
+)
+
+var (
+	// ErrFull is returned by Append if the record does not fit into the
+	// queue.
+	ErrFull = errors.New("disk queue is full")
+
+	// ErrClosed is returned when the queue is used after Close.
+	ErrClosed = errors.New("disk queue is closed")
+
+	// ErrLocked is returned by Open if the queue is open already, by this
+	// or another process.
+	ErrLocked = errors.New("disk queue is locked")
+
+	errCorrupted = errors.New("corrupted record")
+)
+
+// Options configure a Queue.
+type Options struct {
+	// MaxSegmentSize is the size in bytes after which a new segment file
+	// is started. Default is 8 MiB, or MaxSize if it is smaller.
+	MaxSegmentSize int64
+
+	// MaxSize is the maximum total size in bytes of uncommitted records.
+	// Committed records of the oldest segment file are only removed when
+	// the file is, so the files may take up to MaxSegmentSize more.
+	// Default is 256 MiB.
+	MaxSize int64
+
+	// Sync makes Append flush every record to stable storage.
+	Sync bool
+}
+
+// Position identifies a record in the queue.
+type Position struct {
+	Segment uint64
+	Offset  int64
+}
+
+// Queue is a persistent FIFO queue. It is safe for concurrent use.
+type Queue struct {
+	dir string
+	o   Options
+
+	lock *os.File // exclusively locked until Close
+
+	mu       sync.Mutex
+	segments []uint64 // sequence numbers of the segment files, oldest first
+	sizes    map[uint64]int64
+	w        segmentFile
+	read     Position // position of the next record to be read
+	commit   Position // persisted read position
+	dropped  int      // number of records skipped because of corruption
+	closed   bool
+}
+
+// segmentFile is the segment file being written.
+type segmentFile interface {
+	io.WriteCloser
+	Truncate(size int64) error
+	Sync() error
+}
+
+// Open opens the queue stored in dir, creating dir if needed. Records that
+// were appended but not committed before the queue was last closed, or
+// before the process crashed, are read again. The queue is locked until it
+// is closed, so Open returns ErrLocked if it is open already.
+func Open(dir string, o Options) (*Queue, error) {
+	if o.MaxSegmentSize <= 0 {
+		o.MaxSegmentSize = defaultMaxSegmentSize
+	}
+	if o.MaxSize <= 0 {
+		o.MaxSize = defaultMaxSize
+	}
+	if o.MaxSegmentSize > o.MaxSize {
+		o.MaxSegmentSize = o.MaxSize
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	lock, err := lockFile(filepath.Join(dir, lockFileName))
+	if err != nil {
+		return nil, err
+	}
+	q := &Queue{
+		dir:   dir,
+		o:     o,
+		lock:  lock,
+		sizes: make(map[uint64]int64),
+	}
+	if err := q.load(); err != nil {
+		lock.Close()
+		return nil, err
+	}
+	return q, nil
+}
+
+// load discovers the segment files, restores the read position and
+// repairs a torn tail of the last segment.
+func (q *Queue) load() error {
+	infos, err := ioutil.ReadDir(q.dir)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		name := info.Name()
+		if info.IsDir() || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, segmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		q.segments = append(q.segments, seq)
+		q.sizes[seq] = info.Size()
+	}
+	sort.Slice(q.segments, func(i, j int) bool { return q.segments[i] < q.segments[j] })
+
+	if len(q.segments) == 0 {
+		return q.newSegment(1)
+	}
+
+	q.commit = q.loadCursor()
+	q.read = q.commit
+
+	last := q.segments[len(q.segments)-1]
+	valid, err := q.validLength(last)
+	if err != nil {
+		return err
+	}
+	if valid < q.sizes[last] {
+		if err := os.Truncate(q.segmentPath(last), valid); err != nil {
+			return err
+		}
+		q.sizes[last] = valid
+	}
+	f, err := os.OpenFile(q.segmentPath(last), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	q.w = f
+	return nil
+}
+
+// loadCursor returns the persisted read position. If it is missing,
+// unreadable or stale, reading starts at the oldest segment.
+func (q *Queue) loadCursor() Position {
+	start := Position{Segment: q.segments[0]}
+	b, err := ioutil.ReadFile(filepath.Join(q.dir, cursorFileName))
+	if err != nil || len(b) != 20 {
+		return start
+	}
+	pos := Position{
+		Segment: binary.BigEndian.Uint64(b[0:8]),
+		Offset:  int64(binary.BigEndian.Uint64(b[8:16])),
+	}
+	if crc32.ChecksumIEEE(b[0:16]) != binary.BigEndian.Uint32(b[16:20]) {
+		return start
+	}
+	size, ok := q.sizes[pos.Segment]
+	if !ok || pos.Offset > size {
+		return start
+	}
+	return pos
+}
+
+// validLength returns the length of the longest prefix of the segment
+// that consists of intact records.
+func (q *Queue) validLength(seq uint64) (int64, error) {
+	f, err := os.Open(q.segmentPath(seq))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	var off int64
+	for {
+		n, err := readRecord(r, q.o.MaxSize, nil)
+		if err != nil {
+			return off, nil
+		}
+		off += n
+	}
+}
+
+// Append adds a record to the tail of the queue.
+func (q *Queue) Append(record []byte) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+	n := int64(headerSize + len(record))
+	if q.uncommitted()+n > q.o.MaxSize {
+		return ErrFull
+	}
+	cur := q.segments[len(q.segments)-1]
+	if q.sizes[cur] > 0 && q.sizes[cur]+n > q.o.MaxSegmentSize {
+		if err := q.w.Close(); err != nil {
+			return err
+		}
+		if err := q.newSegment(cur + 1); err != nil {
+			return err
+		}
+		cur++
+	}
+
+	buf := make([]byte, n)
+	binary.BigEndian.PutUint32(buf[0:4], uint32(len(record)))
+	binary.BigEndian.PutUint32(buf[4:8], crc32.ChecksumIEEE(record))
+	copy(buf[headerSize:], record)
+	written, err := q.w.Write(buf)
+	if err != nil {
+		q.discardTorn(cur, int64(written))
+		return err
+	}
+	q.sizes[cur] += n
+	if q.o.Sync {
+		return q.w.Sync()
+	}
+	return nil
+}
+
+// discardTorn removes the written bytes of a record that could not be
+// written completely from the end of the segment cur, so that the records
+// appended next can be read. If the segment cannot be truncated, the torn
+// bytes are kept and writing continues in a new segment. q.mu must be
+// held.
+func (q *Queue) discardTorn(cur uint64, written int64) {
+	if written == 0 || q.w.Truncate(q.sizes[cur]) == nil {
+		return
+	}
+	q.sizes[cur] += written
+	torn := q.w
+	if q.newSegment(cur+1) == nil {
+		torn.Close()
+	}
+}
+
+// Read returns up to max records following the last record returned by
+// Read, and the position after the last returned record. The records are
+// not removed from the queue until that position is committed.
+// Corrupted records are skipped.
+func (q *Queue) Read(max int) ([][]byte, Position, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return nil, q.read, ErrClosed
+	}
+	var records [][]byte
+	for len(records) < max {
+		if q.read.Offset >= q.sizes[q.read.Segment] {
+			next, ok := q.nextSegment(q.read.Segment)
+			if !ok {
+				break
+			}
+			q.read = Position{Segment: next}
+			continue
+		}
+		var err error
+		records, err = q.readSegment(records, max)
+		if err == nil {
+			continue
+		}
+		// The rest of the segment cannot be trusted. Continue with the
+		// next segment, or stop if this is the one being written.
+		next, ok := q.nextSegment(q.read.Segment)
+		if !ok {
+			break
+		}
+		q.dropped++
+		q.read = Position{Segment: next}
+	}
+	return records, q.read, nil
+}
+
+// readSegment appends records of the segment at the read position to
+// records until max records are collected or the end of the segment is
+// reached. q.mu must be held.
+func (q *Queue) readSegment(records [][]byte, max int) ([][]byte, error) {
+	f, err := os.Open(q.segmentPath(q.read.Segment))
+	if err != nil {
+		return records, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(q.read.Offset, io.SeekStart); err != nil {
+		return records, err
+	}
+	r := bufio.NewReader(io.LimitReader(f, q.sizes[q.read.Segment]-q.read.Offset))
+	for len(records) < max && q.read.Offset < q.sizes[q.read.Segment] {
+		var rec []byte
+		n, err := readRecord(r, q.o.MaxSize, &rec)
+		if err != nil {
+			return records, err
+		}
+		records = append(records, rec)
+		q.read.Offset += n
+	}
+	return records, nil
+}
+
+// Rewind makes the next Read start again at the last committed position.
+// It is used when the records returned by Read could not be delivered.
+func (q *Queue) Rewind() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.read = q.commit
+}
+
+// Commit persists pos as the read position and removes segment files that
+// precede it.
+func (q *Queue) Commit(pos Position) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrClosed
+	}
+	b := make([]byte, 20)
+	binary.BigEndian.PutUint64(b[0:8], pos.Segment)
+	binary.BigEndian.PutUint64(b[8:16], uint64(pos.Offset))
+	binary.BigEndian.PutUint32(b[16:20], crc32.ChecksumIEEE(b[0:16]))
+	tmp := filepath.Join(q.dir, cursorFileName+".tmp")
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(q.dir, cursorFileName)); err != nil {
+		return err
+	}
+	q.commit = pos
+
+	for len(q.segments) > 1 && q.segments[0] < pos.Segment {
+		seq := q.segments[0]
+		if err := os.Remove(q.segmentPath(seq)); err != nil {
+			return err
+		}
+		delete(q.sizes, seq)
+		q.segments = q.segments[1:]
+	}
+	return nil
+}
+
+// Len returns the number of bytes of uncommitted records. It is zero if
+// the queue is empty.
+func (q *Queue) Len() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.uncommitted()
+}
+
+// uncommitted returns the number of bytes of uncommitted records. q.mu
+// must be held.
+func (q *Queue) uncommitted() int64 {
+	var n int64
+	for _, seq := range q.segments {
+		switch {
+		case seq < q.commit.Segment:
+		case seq == q.commit.Segment:
+			n += q.sizes[seq] - q.commit.Offset
+		default:
+			n += q.sizes[seq]
+		}
+	}
+	return n
+}
+
+// Dropped returns the number of times corrupted data was skipped.
+func (q *Queue) Dropped() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.dropped
+}
+
+// Close closes the segment being written. Uncommitted records are kept.
+func (q *Queue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return nil
+	}
+	q.closed = true
+	err := q.w.Close()
+	if lerr := q.lock.Close(); err == nil {
+		err = lerr
+	}
+	return err
+}
+
+func (q *Queue) newSegment(seq uint64) error {
+	f, err := os.OpenFile(q.segmentPath(seq), os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	q.w = f
+	q.segments = append(q.segments, seq)
+	q.sizes[seq] = 0
+	return nil
+}
+
+func (q *Queue) nextSegment(seq uint64) (uint64, bool) {
+	for _, s := range q.segments {
+		if s > seq {
+			return s, true
+		}
+	}
+	return 0, false
+}
+
+func (q *Queue) segmentPath(seq uint64) string {
+	return filepath.Join(q.dir, fmt.Sprintf("%020d%s", seq, segmentSuffix))
+}
+
+// readRecord reads a single record of at most max bytes from r and stores
+// its payload in rec, if rec is not nil. It returns the number of bytes consumed.
+func readRecord(r io.Reader, max int64, rec *[]byte) (int64, error) {
+	var header [headerSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, err
+	}
+	size := binary.BigEndian.Uint32(header[0:4])
+	if int64(size) > max {
+		return 0, errCorrupted
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return 0, err
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+		return 0, errCorrupted
+	}
+	if rec != nil {
+		*rec = payload
+	}
+	return int64(headerSize) + int64(size), nil
+}
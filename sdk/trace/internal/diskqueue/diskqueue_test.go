@@ -0,0 +1,307 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diskqueue
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "diskqueue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func open(t *testing.T, dir string, o Options) *Queue {
+	q, err := Open(dir, o)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	return q
+}
+
+func appendN(t *testing.T, q *Queue, from, to int) {
+	for i := from; i < to; i++ {
+		if err := q.Append([]byte(fmt.Sprintf("record-%d", i))); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+}
+
+func readAll(t *testing.T, q *Queue) ([]string, Position) {
+	var got []string
+	var pos Position
+	for {
+		records, p, err := q.Read(3)
+		if err != nil {
+			t.Fatalf("Read: %v", err)
+		}
+		pos = p
+		if len(records) == 0 {
+			return got, pos
+		}
+		for _, r := range records {
+			got = append(got, string(r))
+		}
+	}
+}
+
+func checkRecords(t *testing.T, got []string, from, to int) {
+	t.Helper()
+	if len(got) != to-from {
+		t.Fatalf("got %d records %v, want %d", len(got), got, to-from)
+	}
+	for i, r := range got {
+		if want := fmt.Sprintf("record-%d", from+i); r != want {
+			t.Errorf("record %d: got %q, want %q", i, r, want)
+		}
+	}
+}
+
+func TestQueue_ReadCommit(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	q := open(t, dir, Options{MaxSegmentSize: 64})
+	appendN(t, q, 0, 10)
+	got, pos := readAll(t, q)
+	checkRecords(t, got, 0, 10)
+	if err := q.Commit(pos); err != nil {
+		t.Fatal(err)
+	}
+	if n := q.Len(); n != 0 {
+		t.Errorf("Len after commit: got %d, want 0", n)
+	}
+	if n := len(q.segments); n != 1 {
+		t.Errorf("segments after commit: got %d, want 1", n)
+	}
+	appendN(t, q, 10, 12)
+	got, _ = readAll(t, q)
+	checkRecords(t, got, 10, 12)
+	if err := q.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQueue_Rewind(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	q := open(t, dir, Options{})
+	defer q.Close()
+	appendN(t, q, 0, 5)
+	records, pos, _ := q.Read(2)
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2", len(records))
+	}
+	if err := q.Commit(pos); err != nil {
+		t.Fatal(err)
+	}
+	_, _, _ = q.Read(2)
+	q.Rewind()
+	got, _ := readAll(t, q)
+	checkRecords(t, got, 2, 5)
+}
+
+func TestQueue_ReopenRedeliversUncommitted(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	q := open(t, dir, Options{MaxSegmentSize: 64})
+	appendN(t, q, 0, 10)
+	records, pos, _ := q.Read(4)
+	if len(records) != 4 {
+		t.Fatalf("got %d records, want 4", len(records))
+	}
+	if err := q.Commit(pos); err != nil {
+		t.Fatal(err)
+	}
+	// Read but never commit, then "crash", which releases the lock like
+	// Close does.
+	_, _, _ = q.Read(4)
+	q.Close()
+
+	q = open(t, dir, Options{MaxSegmentSize: 64})
+	defer q.Close()
+	got, _ := readAll(t, q)
+	checkRecords(t, got, 4, 10)
+}
+
+func TestQueue_TornWrite(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	q := open(t, dir, Options{})
+	appendN(t, q, 0, 3)
+	q.Close()
+
+	// Simulate a crash in the middle of writing a record.
+	f, err := os.OpenFile(q.segmentPath(1), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.Write([]byte{0, 0, 0, 100, 1, 2})
+	f.Close()
+
+	q = open(t, dir, Options{})
+	defer q.Close()
+	appendN(t, q, 3, 5)
+	got, _ := readAll(t, q)
+	checkRecords(t, got, 0, 5)
+}
+
+// tornFile is a segment file whose next write stops halfway with an error.
+type tornFile struct {
+	segmentFile
+	truncateErr error
+}
+
+func (f *tornFile) Write(p []byte) (int, error) {
+	n, _ := f.segmentFile.Write(p[:len(p)/2])
+	return n, errors.New("disk full")
+}
+
+func (f *tornFile) Truncate(size int64) error {
+	if f.truncateErr != nil {
+		return f.truncateErr
+	}
+	return f.segmentFile.Truncate(size)
+}
+
+func TestQueue_FailedAppend(t *testing.T) {
+	for _, truncateErr := range []error{nil, errors.New("truncate failed")} {
+		dir := tempDir(t)
+		defer os.RemoveAll(dir)
+
+		q := open(t, dir, Options{})
+		appendN(t, q, 0, 2)
+		q.w = &tornFile{segmentFile: q.w, truncateErr: truncateErr}
+		if err := q.Append([]byte("torn")); err == nil {
+			t.Fatal("Append with failed write: got no error")
+		}
+		// Unless writing continues in a new segment, the next write works.
+		if torn, ok := q.w.(*tornFile); ok {
+			q.w = torn.segmentFile
+		}
+		// The records appended after the failed one can be read.
+		appendN(t, q, 2, 4)
+		got, _ := readAll(t, q)
+		checkRecords(t, got, 0, 4)
+		q.Close()
+	}
+}
+
+func TestQueue_Locked(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	q := open(t, dir, Options{})
+	if _, err := Open(dir, Options{}); err != ErrLocked {
+		t.Errorf("Open of open queue: got %v, want %v", err, ErrLocked)
+	}
+	q.Close()
+	q = open(t, dir, Options{})
+	q.Close()
+}
+
+func TestQueue_CorruptedSegment(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	// Every record gets its own segment.
+	q := open(t, dir, Options{MaxSegmentSize: 1})
+	appendN(t, q, 0, 3)
+	q.Close()
+
+	// Flip a payload byte of the first record.
+	path := q.segmentPath(1)
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b[headerSize] ^= 0xff
+	if err := ioutil.WriteFile(path, b, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	q = open(t, dir, Options{MaxSegmentSize: 1})
+	defer q.Close()
+	got, _ := readAll(t, q)
+	checkRecords(t, got, 1, 3)
+	if n := q.Dropped(); n != 1 {
+		t.Errorf("Dropped: got %d, want 1", n)
+	}
+}
+
+func TestQueue_CorruptedCursor(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	q := open(t, dir, Options{})
+	appendN(t, q, 0, 3)
+	_, pos, _ := q.Read(2)
+	if err := q.Commit(pos); err != nil {
+		t.Fatal(err)
+	}
+	q.Close()
+	if err := ioutil.WriteFile(filepath.Join(dir, cursorFileName), []byte("garbage"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// At-least-once: an unreadable cursor restarts from the beginning.
+	q = open(t, dir, Options{})
+	defer q.Close()
+	got, _ := readAll(t, q)
+	checkRecords(t, got, 0, 3)
+}
+
+func TestQueue_Full(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	q := open(t, dir, Options{MaxSize: 40})
+	defer q.Close()
+	appendN(t, q, 0, 2)
+	if err := q.Append([]byte("record-2")); err != ErrFull {
+		t.Errorf("Append: got %v, want %v", err, ErrFull)
+	}
+}
+
+func TestQueue_CommittedRecordsFreeSpace(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	q := open(t, dir, Options{MaxSize: 64})
+	defer q.Close()
+	// Every round appends more than MaxSize in total over the test.
+	for i := 0; i < 20; i += 2 {
+		appendN(t, q, i, i+2)
+		got, pos := readAll(t, q)
+		checkRecords(t, got, i, i+2)
+		if err := q.Commit(pos); err != nil {
+			t.Fatalf("Commit: %v", err)
+		}
+		if n := q.Len(); n != 0 {
+			t.Fatalf("Len after Commit: got %d, want 0", n)
+		}
+	}
+}
@@ -16,6 +16,8 @@ package trace
 
 import (
 	"github.com/hashicorp/golang-lru/simplelru"
+
+	apitag "go.opentelemetry.io/api/tag"
 )
 
 type lruMap struct {
@@ -35,3 +37,29 @@ func (lm *lruMap) add(key, value interface{}) {
 		lm.droppedCount++
 	}
 }
+
+// apply applies the mutator with the semantics of tag.Map.Apply and
+// reports whether it stored the value of the mutator. Entries evicted to
+// make room for an inserted one are counted as dropped, deleted entries are
+// not.
+func (lm *lruMap) apply(mutator apitag.Mutator) bool {
+	key := mutator.KeyValue.Key
+	switch mutator.MutatorOp {
+	case apitag.INSERT:
+		if lm.simpleLruMap.Contains(key) {
+			return false
+		}
+	case apitag.UPDATE:
+		if !lm.simpleLruMap.Contains(key) {
+			return false
+		}
+	case apitag.UPSERT:
+	case apitag.DELETE:
+		lm.simpleLruMap.Remove(key)
+		return false
+	default:
+		return false
+	}
+	lm.add(key, mutator.KeyValue.Value)
+	return true
+}
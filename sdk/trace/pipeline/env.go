@@ -0,0 +1,303 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/exporter/trace/jaeger"
+	"go.opentelemetry.io/exporter/trace/stdout"
+	"go.opentelemetry.io/sdk/resource"
+	"go.opentelemetry.io/sdk/trace"
+)
+
+// Environment variables read by FromEnv.
+const (
+	// EnvServiceName sets the service.name resource attribute. It takes
+	// precedence over resource.EnvAttributes.
+	EnvServiceName = "OTEL_SERVICE_NAME"
+
+	// EnvSampler is the name of the default sampler: always_on,
+	// always_off, traceidratio, parentbased_always_on,
+	// parentbased_always_off or parentbased_traceidratio.
+	EnvSampler = "OTEL_TRACES_SAMPLER"
+	// EnvSamplerArg is the sampling probability of the traceidratio
+	// samplers, between 0 and 1. Default is 1.
+	EnvSamplerArg = "OTEL_TRACES_SAMPLER_ARG"
+
+	EnvAttributeCountLimit = "OTEL_SPAN_ATTRIBUTE_COUNT_LIMIT"
+	EnvEventCountLimit     = "OTEL_SPAN_EVENT_COUNT_LIMIT"
+	EnvLinkCountLimit      = "OTEL_SPAN_LINK_COUNT_LIMIT"
+
+	EnvEventAttributeCountLimit = "OTEL_EVENT_ATTRIBUTE_COUNT_LIMIT"
+	EnvLinkAttributeCountLimit  = "OTEL_LINK_ATTRIBUTE_COUNT_LIMIT"
+	// EnvAttributeValueLengthLimit is the max length in bytes of string
+	// and byte attribute values.
+	EnvAttributeValueLengthLimit = "OTEL_ATTRIBUTE_VALUE_LENGTH_LIMIT"
+
+	// EnvBatchScheduleDelay is the delay between two exports of the batch
+	// span processors in milliseconds.
+	EnvBatchScheduleDelay      = "OTEL_BSP_SCHEDULE_DELAY"
+	EnvBatchMaxQueueSize       = "OTEL_BSP_MAX_QUEUE_SIZE"
+	EnvBatchMaxExportBatchSize = "OTEL_BSP_MAX_EXPORT_BATCH_SIZE"
+	// EnvBatchExportTimeout is the time a batch may take to export in
+	// milliseconds.
+	EnvBatchExportTimeout = "OTEL_BSP_EXPORT_TIMEOUT"
+
+	// EnvExporters is a comma separated list of exporters: stdout, jaeger
+	// or none. Default is none.
+	EnvExporters = "OTEL_TRACES_EXPORTER"
+
+	// EnvStdoutFormat is the format of the stdout exporter: json,
+	// json-pretty or text. Default is json.
+	EnvStdoutFormat = "OTEL_EXPORTER_STDOUT_FORMAT"
+
+	// EnvJaegerEndpoint is the URL of the Jaeger HTTP Thrift collector.
+	// If it is not set, spans are sent to a Jaeger agent.
+	EnvJaegerEndpoint  = "OTEL_EXPORTER_JAEGER_ENDPOINT"
+	EnvJaegerAgentHost = "OTEL_EXPORTER_JAEGER_AGENT_HOST"
+	EnvJaegerAgentPort = "OTEL_EXPORTER_JAEGER_AGENT_PORT"
+	EnvJaegerUser      = "OTEL_EXPORTER_JAEGER_USER"
+	EnvJaegerPassword  = "OTEL_EXPORTER_JAEGER_PASSWORD"
+)
+
+const (
+	defaultJaegerAgentHost = "localhost"
+	defaultJaegerAgentPort = "6831"
+)
+
+// EnvConfig is the tracing configuration read from environment variables.
+// Fields of variables that are not set are zero.
+type EnvConfig struct {
+	// Config is the configuration of the TracerProvider. The resource is
+	// not part of it, it is detected by FromEnv.
+	Config trace.Config
+
+	// Batch are the options of the batch span processor of every
+	// exporter.
+	Batch trace.BatchSpanProcessorOptions
+
+	// Exporters are the names of the selected exporters.
+	Exporters []string
+
+	// Stdout are the options of the stdout exporter.
+	Stdout stdout.Options
+
+	// Jaeger are the options of the Jaeger exporter.
+	Jaeger jaeger.Options
+}
+
+// EnvOptions are the options of FromEnv.
+type EnvOptions struct {
+	// LookupEnv looks up an environment variable.
+	// Default is os.LookupEnv.
+	LookupEnv func(key string) (string, bool)
+
+	// Detectors detect the resource, in addition to EnvServiceName and
+	// resource.EnvAttributes.
+	// Default is the host, process and container detectors.
+	Detectors []resource.Detector
+
+	// OnError is the hook to be called when a detector fails. The
+	// resource detected by the other detectors is used.
+	// If no custom hook is set, errors are logged.
+	// Optional.
+	OnError func(err error)
+
+	// Stdout is the destination of the stdout exporter.
+	// Default is os.Stdout.
+	Stdout io.Writer
+}
+
+// ReadEnv reads the tracing configuration from environment variables. It
+// reports all invalid values in the returned error.
+func ReadEnv(lookup func(key string) (string, bool)) (*EnvConfig, error) {
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	r := &envReader{lookup: lookup}
+	c := &EnvConfig{}
+
+	c.Config.DefaultSampler = r.sampler()
+	c.Config.MaxAttributesPerSpan = r.positiveInt(EnvAttributeCountLimit)
+	c.Config.MaxEventsPerSpan = r.positiveInt(EnvEventCountLimit)
+	c.Config.MaxLinksPerSpan = r.positiveInt(EnvLinkCountLimit)
+	c.Config.MaxAttributesPerEvent = r.positiveInt(EnvEventAttributeCountLimit)
+	c.Config.MaxAttributesPerLink = r.positiveInt(EnvLinkAttributeCountLimit)
+	c.Config.MaxAttributeValueLength = r.positiveInt(EnvAttributeValueLengthLimit)
+
+	c.Batch.ScheduledDelayMillis = time.Duration(r.positiveInt(EnvBatchScheduleDelay)) * time.Millisecond
+	c.Batch.MaxQueueSize = r.positiveInt(EnvBatchMaxQueueSize)
+	c.Batch.MaxExportBatchSize = r.positiveInt(EnvBatchMaxExportBatchSize)
+	c.Batch.ExportTimeout = time.Duration(r.positiveInt(EnvBatchExportTimeout)) * time.Millisecond
+
+	if v, ok := r.get(EnvExporters); ok {
+		for _, name := range strings.Split(v, ",") {
+			switch name = strings.TrimSpace(name); name {
+			case "none", "":
+			case "stdout", "jaeger":
+				c.Exporters = append(c.Exporters, name)
+			default:
+				r.invalid(EnvExporters, v, fmt.Errorf("unknown exporter %q", name))
+			}
+		}
+	}
+
+	if v, ok := r.get(EnvStdoutFormat); ok {
+		switch v {
+		case "json":
+		case "json-pretty":
+			c.Stdout.PrettyPrint = true
+		case "text":
+			c.Stdout.Format = stdout.FormatText
+		default:
+			r.invalid(EnvStdoutFormat, v, errors.New("unknown format"))
+		}
+	}
+
+	if v, ok := r.get(EnvJaegerEndpoint); ok {
+		c.Jaeger.CollectorEndpoint = v
+	} else {
+		host, ok := r.get(EnvJaegerAgentHost)
+		if !ok {
+			host = defaultJaegerAgentHost
+		}
+		port, ok := r.get(EnvJaegerAgentPort)
+		if !ok {
+			port = defaultJaegerAgentPort
+		} else if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+			r.invalid(EnvJaegerAgentPort, port, err)
+		}
+		c.Jaeger.AgentEndpoint = net.JoinHostPort(host, port)
+	}
+	c.Jaeger.Username, _ = r.get(EnvJaegerUser)
+	c.Jaeger.Password, _ = r.get(EnvJaegerPassword)
+
+	if len(r.errs) > 0 {
+		return nil, errors.New(strings.Join(r.errs, "; "))
+	}
+	return c, nil
+}
+
+// FromEnv builds a pipeline from the configuration read by ReadEnv. Every
+// selected exporter gets its own batch span processor. The resource is
+// detected by the given detectors and extended by EnvServiceName and
+// resource.EnvAttributes.
+func FromEnv(o EnvOptions) (*Pipeline, error) {
+	lookup := o.LookupEnv
+	if lookup == nil {
+		lookup = os.LookupEnv
+	}
+	c, err := ReadEnv(lookup)
+	if err != nil {
+		return nil, err
+	}
+
+	detectors := o.Detectors
+	if detectors == nil {
+		detectors = []resource.Detector{resource.Host{}, resource.Process{}, resource.Container{}}
+	}
+	detectors = append([]resource.Detector{resource.Env{LookupEnv: lookup}}, detectors...)
+	res := detectResource(detectors, o.OnError)
+	if v, ok := lookup(EnvServiceName); ok && v != "" {
+		res = resource.Merge(resource.New(resource.ServiceName.String(v)), res)
+	}
+	c.Config.Resource = res
+
+	p := &Pipeline{Provider: trace.NewTracerProvider(trace.WithConfig(c.Config))}
+	for _, name := range c.Exporters {
+		var err error
+		switch name {
+		case "stdout":
+			so := c.Stdout
+			so.Writer = o.Stdout
+			err = p.addStdout(so, true, c.Batch)
+		case "jaeger":
+			err = p.addJaeger(c.Jaeger, true, c.Batch)
+		}
+		if err != nil {
+			p.Shutdown()
+			return nil, fmt.Errorf("%s exporter: %v", name, err)
+		}
+	}
+	return p, nil
+}
+
+// envReader looks up environment variables and collects the errors of
+// invalid values.
+type envReader struct {
+	lookup func(string) (string, bool)
+	errs   []string
+}
+
+// get returns the trimmed value of the variable and whether it is set
+// and not empty.
+func (r *envReader) get(name string) (string, bool) {
+	v, ok := r.lookup(name)
+	v = strings.TrimSpace(v)
+	return v, ok && v != ""
+}
+
+func (r *envReader) invalid(name, value string, err error) {
+	r.errs = append(r.errs, fmt.Sprintf("%s: invalid value %q: %v", name, value, err))
+}
+
+func (r *envReader) positiveInt(name string) int {
+	v, ok := r.get(name)
+	if !ok {
+		return 0
+	}
+	n, err := strconv.Atoi(v)
+	if err == nil && n <= 0 {
+		err = errors.New("must be positive")
+	}
+	if err != nil {
+		r.invalid(name, v, err)
+		return 0
+	}
+	return n
+}
+
+func (r *envReader) sampler() trace.Sampler {
+	name, ok := r.get(EnvSampler)
+	if !ok {
+		return nil
+	}
+	sampler, err := newSampler(name, nil)
+	if err != nil {
+		r.invalid(EnvSampler, name, err)
+		return nil
+	}
+	v, ok := r.get(EnvSamplerArg)
+	if !ok {
+		return sampler
+	}
+	arg, err := strconv.ParseFloat(v, 64)
+	if err == nil {
+		sampler, err = newSampler(name, &arg)
+	}
+	if err != nil {
+		r.invalid(EnvSamplerArg, v, err)
+		return nil
+	}
+	return sampler
+}
@@ -0,0 +1,272 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/api/core"
+	apitrace "go.opentelemetry.io/api/trace"
+	"go.opentelemetry.io/exporter/trace/stdout"
+	"go.opentelemetry.io/sdk/resource"
+	"go.opentelemetry.io/sdk/trace"
+)
+
+func lookupEnv(env map[string]string) func(string) (string, bool) {
+	return func(k string) (string, bool) {
+		v, ok := env[k]
+		return v, ok
+	}
+}
+
+func TestReadEnv(t *testing.T) {
+	c, err := ReadEnv(lookupEnv(map[string]string{
+		EnvSampler:                   "parentbased_traceidratio",
+		EnvSamplerArg:                "0.25",
+		EnvAttributeCountLimit:       "10",
+		EnvEventCountLimit:           "20",
+		EnvLinkCountLimit:            "30",
+		EnvAttributeValueLengthLimit: "1024",
+		EnvBatchScheduleDelay:        "250",
+		EnvBatchMaxQueueSize:         "100",
+		EnvBatchMaxExportBatchSize:   "50",
+		EnvBatchExportTimeout:        "2000",
+		EnvExporters:                 "stdout, jaeger",
+		EnvStdoutFormat:              "text",
+		EnvJaegerAgentHost:           "jaeger",
+		EnvJaegerAgentPort:           "6832",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Config.DefaultSampler == nil {
+		t.Error("sampler: got nil, want traceidratio sampler")
+	} else {
+		if c.Config.DefaultSampler.ShouldSample(trace.SamplingParameters{TraceID: core.TraceID{High: 1 << 63}}).Sampled() {
+			t.Error("traceidratio sampler sampled trace ID above the ratio")
+		}
+		parent := core.SpanContext{TraceID: core.TraceID{High: 1 << 63}, SpanID: 1, TraceFlags: core.TraceFlagsSampled}
+		if !c.Config.DefaultSampler.ShouldSample(trace.SamplingParameters{ParentContext: parent, TraceID: parent.TraceID}).Sampled() {
+			t.Error("parent based sampler did not sample child of sampled parent")
+		}
+	}
+	if got, want := c.Config.MaxAttributesPerSpan, 10; got != want {
+		t.Errorf("MaxAttributesPerSpan: got %d, want %d", got, want)
+	}
+	if got, want := c.Config.MaxEventsPerSpan, 20; got != want {
+		t.Errorf("MaxEventsPerSpan: got %d, want %d", got, want)
+	}
+	if got, want := c.Config.MaxLinksPerSpan, 30; got != want {
+		t.Errorf("MaxLinksPerSpan: got %d, want %d", got, want)
+	}
+	if got, want := c.Config.MaxAttributeValueLength, 1024; got != want {
+		t.Errorf("MaxAttributeValueLength: got %d, want %d", got, want)
+	}
+	if got, want := c.Batch.ScheduledDelayMillis, 250*time.Millisecond; got != want {
+		t.Errorf("ScheduledDelayMillis: got %v, want %v", got, want)
+	}
+	if got, want := c.Batch.MaxQueueSize, 100; got != want {
+		t.Errorf("MaxQueueSize: got %d, want %d", got, want)
+	}
+	if got, want := c.Batch.MaxExportBatchSize, 50; got != want {
+		t.Errorf("MaxExportBatchSize: got %d, want %d", got, want)
+	}
+	if got, want := c.Batch.ExportTimeout, 2*time.Second; got != want {
+		t.Errorf("ExportTimeout: got %v, want %v", got, want)
+	}
+	if got, want := strings.Join(c.Exporters, ","), "stdout,jaeger"; got != want {
+		t.Errorf("Exporters: got %q, want %q", got, want)
+	}
+	if got, want := c.Stdout.Format, stdout.FormatText; got != want {
+		t.Errorf("stdout format: got %v, want %v", got, want)
+	}
+	if got, want := c.Jaeger.AgentEndpoint, "jaeger:6832"; got != want {
+		t.Errorf("Jaeger agent endpoint: got %q, want %q", got, want)
+	}
+}
+
+func TestReadEnvDefaults(t *testing.T) {
+	c, err := ReadEnv(lookupEnv(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Config.DefaultSampler != nil {
+		t.Error("sampler: got sampler, want nil")
+	}
+	if len(c.Exporters) != 0 {
+		t.Errorf("Exporters: got %v, want none", c.Exporters)
+	}
+	if got, want := c.Jaeger.AgentEndpoint, "localhost:6831"; got != want {
+		t.Errorf("Jaeger agent endpoint: got %q, want %q", got, want)
+	}
+}
+
+func TestReadEnvTraceIDRatio(t *testing.T) {
+	c, err := ReadEnv(lookupEnv(map[string]string{
+		EnvSampler:    "traceidratio",
+		EnvSamplerArg: "0.25",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Config.DefaultSampler.ShouldSample(trace.SamplingParameters{TraceID: core.TraceID{High: 1 << 63}}).Sampled() {
+		t.Error("traceidratio sampler sampled trace ID above the ratio")
+	}
+	// Unlike parentbased_traceidratio, the sampler ignores the parent.
+	parent := core.SpanContext{TraceID: core.TraceID{High: 1 << 63}, SpanID: 1, TraceFlags: core.TraceFlagsSampled}
+	if c.Config.DefaultSampler.ShouldSample(trace.SamplingParameters{ParentContext: parent, TraceID: parent.TraceID}).Sampled() {
+		t.Error("traceidratio sampler sampled child of sampled parent above the ratio")
+	}
+}
+
+func TestReadEnvInvalid(t *testing.T) {
+	_, err := ReadEnv(lookupEnv(map[string]string{
+		EnvSampler:             "traceidratio",
+		EnvSamplerArg:          "1.5",
+		EnvAttributeCountLimit: "-1",
+		EnvBatchMaxQueueSize:   "many",
+		EnvExporters:           "stdout,zipkin",
+		EnvStdoutFormat:        "xml",
+		EnvJaegerAgentPort:     "70000",
+	}))
+	if err == nil {
+		t.Fatal("got no error for invalid values")
+	}
+	for _, name := range []string{
+		EnvSamplerArg,
+		EnvAttributeCountLimit,
+		EnvBatchMaxQueueSize,
+		EnvExporters,
+		EnvStdoutFormat,
+		EnvJaegerAgentPort,
+	} {
+		if !strings.Contains(err.Error(), name+":") {
+			t.Errorf("error %q does not report %s", err, name)
+		}
+	}
+
+	for _, sampler := range []string{"sometimes", "parentbased_sometimes"} {
+		_, err = ReadEnv(lookupEnv(map[string]string{EnvSampler: sampler}))
+		if err == nil || !strings.Contains(err.Error(), EnvSampler+":") {
+			t.Errorf("unknown sampler %s: got error %v", sampler, err)
+		}
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	var b bytes.Buffer
+	p, err := FromEnv(EnvOptions{
+		LookupEnv: lookupEnv(map[string]string{
+			EnvServiceName:         "checkout",
+			resource.EnvAttributes: "service.name=ignored,deployment.environment=prod",
+			EnvSampler:             "always_on",
+			EnvExporters:           "stdout",
+			EnvBatchScheduleDelay:  "10",
+			EnvBatchMaxQueueSize:   "10",
+			EnvAttributeCountLimit: "1",
+		}),
+		Detectors: []resource.Detector{},
+		Stdout:    &b,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, span := p.Provider.Tracer().Start(context.Background(), "work")
+	span.End()
+	p.Shutdown()
+
+	var got struct {
+		Name     string
+		Resource []struct{ Key, Value string }
+	}
+	if err := json.Unmarshal(b.Bytes(), &got); err != nil {
+		t.Fatalf("invalid stdout output %q: %v", b.String(), err)
+	}
+	if got.Name != "work" {
+		t.Errorf("span name: got %q, want %q", got.Name, "work")
+	}
+	want := []struct{ Key, Value string }{
+		{"deployment.environment", "prod"},
+		{"service.name", "checkout"},
+	}
+	if len(got.Resource) != len(want) {
+		t.Fatalf("resource: got %v, want %v", got.Resource, want)
+	}
+	for i := range want {
+		if got.Resource[i] != want[i] {
+			t.Errorf("resource: got %v, want %v", got.Resource, want)
+		}
+	}
+}
+
+// failingDetector is a resource.Detector that fails.
+type failingDetector struct{}
+
+func (failingDetector) Detect(context.Context) (*resource.Resource, error) {
+	return nil, errors.New("metadata endpoint unreachable")
+}
+
+func TestFromEnvFailingDetector(t *testing.T) {
+	var errs []error
+	p, err := FromEnv(EnvOptions{
+		LookupEnv: lookupEnv(map[string]string{EnvServiceName: "checkout"}),
+		Detectors: []resource.Detector{failingDetector{}},
+		OnError:   func(err error) { errs = append(errs, err) },
+	})
+	if err != nil {
+		t.Fatalf("failing detector stopped the pipeline: %v", err)
+	}
+	defer p.Shutdown()
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "metadata endpoint unreachable") {
+		t.Errorf("got errors %v, want the detector error", errs)
+	}
+
+	sp := &resourceRecorder{}
+	p.Provider.RegisterSpanProcessor(sp)
+	_, span := p.Provider.Tracer().Start(context.Background(), "work", apitrace.WithRecordEvents())
+	span.End()
+	if sp.res == nil {
+		t.Fatal("span not recorded")
+	}
+	if got := sp.res.Attributes(); len(got) != 1 || got[0].Key != resource.ServiceName || got[0].Value.Emit() != "checkout" {
+		t.Errorf("resource: got %v, want service.name from the environment", got)
+	}
+}
+
+// resourceRecorder is a span processor that records the resource of the
+// last ended span.
+type resourceRecorder struct {
+	res *resource.Resource
+}
+
+func (r *resourceRecorder) OnStart(*trace.SpanData)          {}
+func (r *resourceRecorder) OnEnd(sd *trace.SpanData)         { r.res = sd.Resource }
+func (r *resourceRecorder) Shutdown()                        {}
+func (r *resourceRecorder) ForceFlush(context.Context) error { return nil }
+
+func TestFromEnvInvalid(t *testing.T) {
+	_, err := FromEnv(EnvOptions{
+		LookupEnv: lookupEnv(map[string]string{EnvEventCountLimit: "0"}),
+	})
+	if err == nil {
+		t.Error("got no error for invalid value")
+	}
+}
@@ -0,0 +1,533 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pipeline
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+
+	"go.opentelemetry.io/api/core"
+	"go.opentelemetry.io/api/key"
+	"go.opentelemetry.io/exporter/trace/file"
+	"go.opentelemetry.io/exporter/trace/jaeger"
+	"go.opentelemetry.io/exporter/trace/stdout"
+	"go.opentelemetry.io/sdk/resource"
+	"go.opentelemetry.io/sdk/trace"
+)
+
+// FileConfig is the tracing pipeline described by a configuration file,
+// for example
+//
+//	sampler:
+//	  type: traceidratio
+//	  ratio: 0.1
+//	limits:
+//	  attributes_per_span: 64
+//	  attribute_value_length: 1024
+//	  attribute_value_length_per_key:
+//	    db.statement: 4096
+//	resource:
+//	  service.name: checkout
+//	processors:
+//	  - type: batch
+//	    batch:
+//	      schedule_delay: 1s
+//	    exporter:
+//	      jaeger:
+//	        collector_endpoint: http://localhost:14268/api/traces
+//	  - type: simple
+//	    exporter:
+//	      stdout:
+//	        format: text
+//
+// Files with a .json extension are parsed as JSON using the same field
+// names, all other files as YAML. Unknown fields are errors.
+type FileConfig struct {
+	Sampler    *SamplerConfig    `json:"sampler" yaml:"sampler"`
+	Limits     LimitsConfig      `json:"limits" yaml:"limits"`
+	Resource   map[string]string `json:"resource" yaml:"resource"`
+	Processors []ProcessorConfig `json:"processors" yaml:"processors"`
+}
+
+// SamplerConfig configures the default sampler.
+type SamplerConfig struct {
+	// Type is always_on, always_off, traceidratio, parentbased_always_on,
+	// parentbased_always_off or parentbased_traceidratio.
+	Type string `json:"type" yaml:"type"`
+
+	// Ratio is the fraction of traces sampled by the traceidratio
+	// sampler. Default is 1.
+	Ratio *float64 `json:"ratio" yaml:"ratio"`
+}
+
+// LimitsConfig configures the span limits. Zero values keep the defaults.
+type LimitsConfig struct {
+	AttributesPerSpan  int `json:"attributes_per_span" yaml:"attributes_per_span"`
+	EventsPerSpan      int `json:"events_per_span" yaml:"events_per_span"`
+	LinksPerSpan       int `json:"links_per_span" yaml:"links_per_span"`
+	AttributesPerEvent int `json:"attributes_per_event" yaml:"attributes_per_event"`
+	AttributesPerLink  int `json:"attributes_per_link" yaml:"attributes_per_link"`
+
+	// AttributeValueLength is the max length in bytes of string and byte
+	// attribute values, AttributeValueLengthPerKey overrides it for
+	// single keys. A per-key limit of zero disables truncation.
+	AttributeValueLength       int            `json:"attribute_value_length" yaml:"attribute_value_length"`
+	AttributeValueLengthPerKey map[string]int `json:"attribute_value_length_per_key" yaml:"attribute_value_length_per_key"`
+}
+
+// ProcessorConfig configures a span processor and its exporter.
+type ProcessorConfig struct {
+	// Type is simple or batch.
+	Type string `json:"type" yaml:"type"`
+
+	// Batch are the options of a batch processor.
+	Batch *BatchConfig `json:"batch" yaml:"batch"`
+
+	// Exporter is the exporter the processor sends spans to.
+	Exporter ExporterConfig `json:"exporter" yaml:"exporter"`
+}
+
+// BatchConfig configures a batch span processor. Zero values keep the
+// defaults.
+type BatchConfig struct {
+	// ScheduleDelay is the delay between two exports, such as "5s".
+	ScheduleDelay      string `json:"schedule_delay" yaml:"schedule_delay"`
+	MaxQueueSize       int    `json:"max_queue_size" yaml:"max_queue_size"`
+	MaxExportBatchSize int    `json:"max_export_batch_size" yaml:"max_export_batch_size"`
+
+	// ExportTimeout is the time a batch may take to export, such as "30s".
+	ExportTimeout string `json:"export_timeout" yaml:"export_timeout"`
+}
+
+// ExporterConfig configures an exporter. Exactly one field must be set.
+type ExporterConfig struct {
+	Stdout *StdoutConfig       `json:"stdout" yaml:"stdout"`
+	Jaeger *JaegerConfig       `json:"jaeger" yaml:"jaeger"`
+	File   *FileExporterConfig `json:"file" yaml:"file"`
+}
+
+// StdoutConfig configures a stdout exporter.
+type StdoutConfig struct {
+	// Format is json, json-pretty or text. Default is json.
+	Format string `json:"format" yaml:"format"`
+}
+
+// JaegerConfig configures a Jaeger exporter. Exactly one of
+// CollectorEndpoint and AgentEndpoint must be set.
+type JaegerConfig struct {
+	CollectorEndpoint string `json:"collector_endpoint" yaml:"collector_endpoint"`
+	AgentEndpoint     string `json:"agent_endpoint" yaml:"agent_endpoint"`
+	Username          string `json:"username" yaml:"username"`
+	Password          string `json:"password" yaml:"password"`
+	ServiceName       string `json:"service_name" yaml:"service_name"`
+	BufferMaxCount    int    `json:"buffer_max_count" yaml:"buffer_max_count"`
+}
+
+// FileExporterConfig configures a rotating file exporter.
+type FileExporterConfig struct {
+	Path       string `json:"path" yaml:"path"`
+	MaxSize    int64  `json:"max_size" yaml:"max_size"`
+	MaxAge     string `json:"max_age" yaml:"max_age"`
+	MaxBackups int    `json:"max_backups" yaml:"max_backups"`
+	Compress   bool   `json:"compress" yaml:"compress"`
+}
+
+// FileOptions are the options of FromFile.
+type FileOptions struct {
+	// ReloadInterval is the interval in which the file is checked for
+	// changes. When it changed, its sampler and limits are applied to
+	// the provider. Changes of the resource and the processors require a
+	// restart. Zero disables reloading.
+	ReloadInterval time.Duration
+
+	// OnError is the hook to be called when a changed file cannot be
+	// read or is invalid, in which case the previous configuration stays
+	// in effect, and when a detector fails, in which case the resource
+	// detected by the other detectors is used.
+	// If no custom hook is set, errors are logged.
+	// Optional.
+	OnError func(err error)
+
+	// Detectors detect the resource. Attributes of the file take
+	// precedence.
+	// Default is the host, process and container detectors.
+	Detectors []resource.Detector
+
+	// Stdout is the destination of stdout exporters.
+	// Default is os.Stdout.
+	Stdout io.Writer
+}
+
+// LoadFile reads and validates the configuration file at path.
+func LoadFile(path string) (*FileConfig, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := parseFile(path, b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return c, nil
+}
+
+func parseFile(path string, b []byte) (*FileConfig, error) {
+	c := &FileConfig{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		dec := json.NewDecoder(bytes.NewReader(b))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(c); err != nil {
+			return nil, err
+		}
+	} else if err := yaml.UnmarshalStrict(b, c); err != nil {
+		return nil, err
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Validate checks the configuration. The error names the invalid field,
+// such as processors[1].batch.schedule_delay.
+func (c *FileConfig) Validate() error {
+	if _, err := c.traceConfig(); err != nil {
+		return err
+	}
+	for i, pc := range c.Processors {
+		if err := pc.validate(fmt.Sprintf("processors[%d]", i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// traceConfig returns the sampler and the limits of the configuration.
+func (c *FileConfig) traceConfig() (trace.Config, error) {
+	var tc trace.Config
+	if c.Sampler != nil {
+		s, err := newSampler(c.Sampler.Type, c.Sampler.Ratio)
+		if err != nil {
+			return tc, fmt.Errorf("sampler: %v", err)
+		}
+		tc.DefaultSampler = s
+	}
+	for _, l := range []struct {
+		name  string
+		value int
+		field *int
+	}{
+		{"attributes_per_span", c.Limits.AttributesPerSpan, &tc.MaxAttributesPerSpan},
+		{"events_per_span", c.Limits.EventsPerSpan, &tc.MaxEventsPerSpan},
+		{"links_per_span", c.Limits.LinksPerSpan, &tc.MaxLinksPerSpan},
+		{"attributes_per_event", c.Limits.AttributesPerEvent, &tc.MaxAttributesPerEvent},
+		{"attributes_per_link", c.Limits.AttributesPerLink, &tc.MaxAttributesPerLink},
+		{"attribute_value_length", c.Limits.AttributeValueLength, &tc.MaxAttributeValueLength},
+	} {
+		if l.value < 0 {
+			return tc, fmt.Errorf("limits.%s: must not be negative", l.name)
+		}
+		*l.field = l.value
+	}
+	if len(c.Limits.AttributeValueLengthPerKey) > 0 {
+		tc.MaxAttributeValueLengthPerKey = make(map[core.Key]int, len(c.Limits.AttributeValueLengthPerKey))
+		for k, limit := range c.Limits.AttributeValueLengthPerKey {
+			if limit < 0 {
+				return tc, fmt.Errorf("limits.attribute_value_length_per_key.%s: must not be negative", k)
+			}
+			tc.MaxAttributeValueLengthPerKey[core.Key{Name: k}] = limit
+		}
+	}
+	return tc, nil
+}
+
+// validate checks the processor configuration found at path.
+func (pc *ProcessorConfig) validate(path string) error {
+	switch pc.Type {
+	case "simple":
+		if pc.Batch != nil {
+			return fmt.Errorf("%s.batch: not allowed for simple processor", path)
+		}
+	case "batch":
+		if _, err := pc.batchOptions(); err != nil {
+			return fmt.Errorf("%s.%v", path, err)
+		}
+	default:
+		return fmt.Errorf("%s.type: unknown processor type %q", path, pc.Type)
+	}
+	return pc.Exporter.validate(path + ".exporter")
+}
+
+func (pc *ProcessorConfig) batchOptions() (trace.BatchSpanProcessorOptions, error) {
+	var o trace.BatchSpanProcessorOptions
+	if pc.Batch == nil {
+		return o, nil
+	}
+	for _, d := range []struct {
+		name  string
+		value string
+		field *time.Duration
+	}{
+		{"schedule_delay", pc.Batch.ScheduleDelay, &o.ScheduledDelayMillis},
+		{"export_timeout", pc.Batch.ExportTimeout, &o.ExportTimeout},
+	} {
+		if d.value == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.value)
+		if err == nil && v <= 0 {
+			err = fmt.Errorf("must be positive")
+		}
+		if err != nil {
+			return o, fmt.Errorf("batch.%s: %v", d.name, err)
+		}
+		*d.field = v
+	}
+	if pc.Batch.MaxQueueSize < 0 {
+		return o, fmt.Errorf("batch.max_queue_size: must not be negative")
+	}
+	if pc.Batch.MaxExportBatchSize < 0 {
+		return o, fmt.Errorf("batch.max_export_batch_size: must not be negative")
+	}
+	o.MaxQueueSize = pc.Batch.MaxQueueSize
+	o.MaxExportBatchSize = pc.Batch.MaxExportBatchSize
+	return o, nil
+}
+
+// validate checks the exporter configuration found at path.
+func (ec *ExporterConfig) validate(path string) error {
+	n := 0
+	for _, set := range []bool{ec.Stdout != nil, ec.Jaeger != nil, ec.File != nil} {
+		if set {
+			n++
+		}
+	}
+	if n != 1 {
+		return fmt.Errorf("%s: exactly one of stdout, jaeger and file must be set", path)
+	}
+	var err error
+	switch {
+	case ec.Stdout != nil:
+		if _, err = ec.Stdout.options(); err != nil {
+			err = fmt.Errorf("stdout.format: %v", err)
+		}
+	case ec.Jaeger != nil:
+		if (ec.Jaeger.CollectorEndpoint == "") == (ec.Jaeger.AgentEndpoint == "") {
+			err = fmt.Errorf("jaeger: exactly one of collector_endpoint and agent_endpoint must be set")
+		}
+	case ec.File != nil:
+		_, err = ec.File.options()
+	}
+	if err != nil {
+		return fmt.Errorf("%s.%v", path, err)
+	}
+	return nil
+}
+
+func (sc *StdoutConfig) options() (stdout.Options, error) {
+	var o stdout.Options
+	switch sc.Format {
+	case "", "json":
+	case "json-pretty":
+		o.PrettyPrint = true
+	case "text":
+		o.Format = stdout.FormatText
+	default:
+		return o, fmt.Errorf("unknown format %q", sc.Format)
+	}
+	return o, nil
+}
+
+func (jc *JaegerConfig) options() jaeger.Options {
+	return jaeger.Options{
+		CollectorEndpoint: jc.CollectorEndpoint,
+		AgentEndpoint:     jc.AgentEndpoint,
+		Username:          jc.Username,
+		Password:          jc.Password,
+		Process:           jaeger.Process{ServiceName: jc.ServiceName},
+		BufferMaxCount:    jc.BufferMaxCount,
+	}
+}
+
+func (fc *FileExporterConfig) options() (file.Options, error) {
+	o := file.Options{
+		Path:       fc.Path,
+		MaxSize:    fc.MaxSize,
+		MaxBackups: fc.MaxBackups,
+		Compress:   fc.Compress,
+	}
+	if fc.Path == "" {
+		return o, fmt.Errorf("file.path: must be set")
+	}
+	if fc.MaxSize < 0 {
+		return o, fmt.Errorf("file.max_size: must not be negative")
+	}
+	if fc.MaxBackups < 0 {
+		return o, fmt.Errorf("file.max_backups: must not be negative")
+	}
+	if fc.MaxAge != "" {
+		d, err := time.ParseDuration(fc.MaxAge)
+		if err == nil && d < 0 {
+			err = fmt.Errorf("must not be negative")
+		}
+		if err != nil {
+			return o, fmt.Errorf("file.max_age: %v", err)
+		}
+		o.MaxAge = d
+	}
+	return o, nil
+}
+
+// FromFile builds a pipeline from the configuration file at path and, if
+// o.ReloadInterval is set, reloads its sampler and limits when the file
+// changes until the pipeline is shut down.
+func FromFile(path string, o FileOptions) (*Pipeline, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := parseFile(path, b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	p, res, err := c.build(o)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if o.ReloadInterval > 0 {
+		onError := o.OnError
+		if onError == nil {
+			onError = func(err error) {
+				log.Printf("Error when reloading tracing configuration: %v", err)
+			}
+		}
+		w := &watcher{
+			path:     path,
+			last:     b,
+			provider: p.Provider,
+			resource: res,
+			onError:  onError,
+			stopCh:   make(chan struct{}),
+			doneCh:   make(chan struct{}),
+		}
+		go w.run(o.ReloadInterval)
+		p.stop = w.stop
+	}
+	return p, nil
+}
+
+// build returns the pipeline of the configuration and the resource of its
+// provider.
+func (c *FileConfig) build(o FileOptions) (*Pipeline, *resource.Resource, error) {
+	tc, err := c.traceConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	detectors := o.Detectors
+	if detectors == nil {
+		detectors = []resource.Detector{resource.Host{}, resource.Process{}, resource.Container{}}
+	}
+	res := detectResource(detectors, o.OnError)
+	kvs := make([]core.KeyValue, 0, len(c.Resource))
+	for k, v := range c.Resource {
+		kvs = append(kvs, key.New(k).String(v))
+	}
+	tc.Resource = resource.Merge(resource.New(kvs...), res)
+
+	p := &Pipeline{Provider: trace.NewTracerProvider(trace.WithConfig(tc))}
+	for i, pc := range c.Processors {
+		bo, _ := pc.batchOptions()
+		batch := pc.Type == "batch"
+		switch ec := pc.Exporter; {
+		case ec.Stdout != nil:
+			so, _ := ec.Stdout.options()
+			so.Writer = o.Stdout
+			err = p.addStdout(so, batch, bo)
+		case ec.Jaeger != nil:
+			err = p.addJaeger(ec.Jaeger.options(), batch, bo)
+		case ec.File != nil:
+			fo, _ := ec.File.options()
+			err = p.addFile(fo, batch, bo)
+		}
+		if err != nil {
+			p.Shutdown()
+			return nil, nil, fmt.Errorf("processors[%d].exporter: %v", i, err)
+		}
+	}
+	return p, tc.Resource, nil
+}
+
+// watcher polls a configuration file and applies the sampler and limits
+// of a changed file to a provider.
+type watcher struct {
+	path     string
+	last     []byte
+	provider *trace.TracerProvider
+	resource *resource.Resource
+	onError  func(err error)
+	stopCh   chan struct{}
+	doneCh   chan struct{}
+}
+
+func (w *watcher) run(interval time.Duration) {
+	defer close(w.doneCh)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stopCh:
+			return
+		case <-ticker.C:
+			w.reload()
+		}
+	}
+}
+
+// reload applies the configuration file if its content changed. The
+// sampler and limits replace the current ones, so that settings removed
+// from the file get their default values again.
+func (w *watcher) reload() {
+	b, err := ioutil.ReadFile(w.path)
+	if err != nil {
+		w.onError(err)
+		return
+	}
+	if bytes.Equal(b, w.last) {
+		return
+	}
+	w.last = b
+	c, err := parseFile(w.path, b)
+	if err != nil {
+		w.onError(fmt.Errorf("%s: %v", w.path, err))
+		return
+	}
+	tc, _ := c.traceConfig()
+	tc.Resource = w.resource
+	w.provider.ResetConfig(tc)
+}
+
+func (w *watcher) stop() {
+	close(w.stopCh)
+	<-w.doneCh
+}
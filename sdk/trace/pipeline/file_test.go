@@ -0,0 +1,354 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pipeline
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"go.opentelemetry.io/sdk/resource"
+)
+
+const testYAML = `
+sampler:
+  type: traceidratio
+  ratio: 0.5
+limits:
+  attributes_per_span: 64
+  attribute_value_length: 1024
+  attribute_value_length_per_key:
+    db.statement: 4096
+resource:
+  service.name: checkout
+processors:
+  - type: batch
+    batch:
+      schedule_delay: 1s
+      max_queue_size: 100
+      export_timeout: 10s
+    exporter:
+      jaeger:
+        collector_endpoint: http://localhost:14268/api/traces
+  - type: simple
+    exporter:
+      stdout:
+        format: text
+`
+
+const testJSON = `{
+  "sampler": {"type": "traceidratio", "ratio": 0.5},
+  "limits": {
+    "attributes_per_span": 64,
+    "attribute_value_length": 1024,
+    "attribute_value_length_per_key": {"db.statement": 4096}
+  },
+  "resource": {"service.name": "checkout"},
+  "processors": [
+    {
+      "type": "batch",
+      "batch": {"schedule_delay": "1s", "max_queue_size": 100, "export_timeout": "10s"},
+      "exporter": {"jaeger": {"collector_endpoint": "http://localhost:14268/api/traces"}}
+    },
+    {"type": "simple", "exporter": {"stdout": {"format": "text"}}}
+  ]
+}`
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pipeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ratio := 0.5
+	want := &FileConfig{
+		Sampler: &SamplerConfig{Type: "traceidratio", Ratio: &ratio},
+		Limits: LimitsConfig{
+			AttributesPerSpan:          64,
+			AttributeValueLength:       1024,
+			AttributeValueLengthPerKey: map[string]int{"db.statement": 4096},
+		},
+		Resource: map[string]string{"service.name": "checkout"},
+		Processors: []ProcessorConfig{
+			{
+				Type:  "batch",
+				Batch: &BatchConfig{ScheduleDelay: "1s", MaxQueueSize: 100, ExportTimeout: "10s"},
+				Exporter: ExporterConfig{Jaeger: &JaegerConfig{
+					CollectorEndpoint: "http://localhost:14268/api/traces",
+				}},
+			},
+			{
+				Type:     "simple",
+				Exporter: ExporterConfig{Stdout: &StdoutConfig{Format: "text"}},
+			},
+		},
+	}
+	for name, content := range map[string]string{
+		"config.yaml": testYAML,
+		"config.json": testJSON,
+	} {
+		got, err := LoadFile(writeFile(t, dir, name, content))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Errorf("%s: -got +want %s", name, diff)
+		}
+	}
+}
+
+func TestFileConfigValidate(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		config string
+		err    string
+	}{
+		{
+			name:   "unknown field",
+			config: "sampler:\n  type: always_on\n  rate: 1\n",
+			err:    "line 3: field rate not found",
+		},
+		{
+			name:   "unknown sampler",
+			config: "sampler:\n  type: sometimes\n",
+			err:    `sampler: unknown sampler "sometimes"`,
+		},
+		{
+			name:   "ratio out of range",
+			config: "sampler:\n  type: traceidratio\n  ratio: 2\n",
+			err:    "sampler: must be between 0 and 1",
+		},
+		{
+			name:   "negative limit",
+			config: "limits:\n  links_per_span: -1\n",
+			err:    "limits.links_per_span: must not be negative",
+		},
+		{
+			name:   "negative per-key limit",
+			config: "limits:\n  attribute_value_length_per_key:\n    db.statement: -1\n",
+			err:    "limits.attribute_value_length_per_key.db.statement: must not be negative",
+		},
+		{
+			name:   "unknown processor",
+			config: "processors:\n  - type: async\n    exporter: {stdout: {}}\n",
+			err:    `processors[0].type: unknown processor type "async"`,
+		},
+		{
+			name:   "invalid delay",
+			config: "processors:\n  - type: simple\n    exporter: {stdout: {}}\n  - type: batch\n    batch: {schedule_delay: soon}\n    exporter: {stdout: {}}\n",
+			err:    "processors[1].batch.schedule_delay: time: invalid duration",
+		},
+		{
+			name:   "batch options of simple processor",
+			config: "processors:\n  - type: simple\n    batch: {max_queue_size: 1}\n    exporter: {stdout: {}}\n",
+			err:    "processors[0].batch: not allowed for simple processor",
+		},
+		{
+			name:   "no exporter",
+			config: "processors:\n  - type: simple\n",
+			err:    "processors[0].exporter: exactly one of stdout, jaeger and file must be set",
+		},
+		{
+			name:   "two exporters",
+			config: "processors:\n  - type: simple\n    exporter: {stdout: {}, file: {path: spans.jsonl}}\n",
+			err:    "processors[0].exporter: exactly one of stdout, jaeger and file must be set",
+		},
+		{
+			name:   "unknown stdout format",
+			config: "processors:\n  - type: simple\n    exporter: {stdout: {format: xml}}\n",
+			err:    `processors[0].exporter.stdout.format: unknown format "xml"`,
+		},
+		{
+			name:   "jaeger without endpoint",
+			config: "processors:\n  - type: simple\n    exporter: {jaeger: {}}\n",
+			err:    "processors[0].exporter.jaeger: exactly one of collector_endpoint and agent_endpoint must be set",
+		},
+		{
+			name:   "file without path",
+			config: "processors:\n  - type: simple\n    exporter: {file: {compress: true}}\n",
+			err:    "processors[0].exporter.file.path: must be set",
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := parseFile("config.yaml", []byte(c.config))
+			if err == nil {
+				t.Fatalf("got no error, want %q", c.err)
+			}
+			if !strings.Contains(err.Error(), c.err) {
+				t.Errorf("got error %q, want %q", err, c.err)
+			}
+		})
+	}
+}
+
+// syncBuffer is a bytes.Buffer that is safe for concurrent use.
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.String()
+}
+
+func TestFromFileReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pipeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const pipeline = `
+resource:
+  service.name: checkout
+processors:
+  - type: simple
+    exporter:
+      stdout:
+        format: text
+`
+	path := writeFile(t, dir, "config.yaml", "sampler: {type: always_off}\n"+pipeline)
+	var out syncBuffer
+	var errs []error
+	var errsMu sync.Mutex
+	p, err := FromFile(path, FileOptions{
+		ReloadInterval: 5 * time.Millisecond,
+		OnError: func(err error) {
+			errsMu.Lock()
+			errs = append(errs, err)
+			errsMu.Unlock()
+		},
+		Detectors: []resource.Detector{},
+		Stdout:    &out,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Shutdown()
+
+	tracer := p.Provider.Tracer()
+	sampled := func() bool {
+		_, span := tracer.Start(context.Background(), "work")
+		defer span.End()
+		return span.SpanContext().IsSampled()
+	}
+	if sampled() {
+		t.Fatal("span sampled by always_off sampler")
+	}
+
+	// An invalid file is reported and keeps the configuration.
+	writeFile(t, dir, "config.yaml", "sampler: {type: sometimes}\n"+pipeline)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		errsMu.Lock()
+		n := len(errs)
+		errsMu.Unlock()
+		if n > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("invalid configuration file not reported")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if sampled() {
+		t.Fatal("span sampled after invalid configuration file")
+	}
+
+	writeFile(t, dir, "config.yaml", "sampler: {type: always_on}\n"+pipeline)
+	for !sampled() {
+		if time.Now().After(deadline) {
+			t.Fatal("sampler not reloaded")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if got := out.String(); !strings.Contains(got, " work ") {
+		t.Errorf("span not exported to stdout: %q", got)
+	}
+}
+
+func TestFromFileReloadRemovedSetting(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pipeline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	const pipeline = `
+processors:
+  - type: simple
+    exporter:
+      stdout:
+        format: text
+`
+	path := writeFile(t, dir, "config.yaml", "sampler: {type: always_on}\n"+pipeline)
+	p, err := FromFile(path, FileOptions{
+		ReloadInterval: 5 * time.Millisecond,
+		Detectors:      []resource.Detector{},
+		Stdout:         ioutil.Discard,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Shutdown()
+
+	tracer := p.Provider.Tracer()
+	sampled := func() bool {
+		_, span := tracer.Start(context.Background(), "work")
+		defer span.End()
+		return span.SpanContext().IsSampled()
+	}
+	if !sampled() {
+		t.Fatal("span not sampled by always_on sampler")
+	}
+
+	// Without a sampler in the file, the default sampler samples root
+	// spans with a probability of 0.0001.
+	writeFile(t, dir, "config.yaml", pipeline)
+	deadline := time.Now().Add(5 * time.Second)
+	for sampled() {
+		if time.Now().After(deadline) {
+			t.Fatal("removed sampler still in effect")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pipeline builds ready to use tracing pipelines, made of a
+// TracerProvider, its span processors and their exporters, from
+// configuration kept outside of the program.
+package pipeline // import "go.opentelemetry.io/sdk/trace/pipeline"
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/exporter/trace/file"
+	"go.opentelemetry.io/exporter/trace/jaeger"
+	"go.opentelemetry.io/exporter/trace/stdout"
+	"go.opentelemetry.io/sdk/resource"
+	"go.opentelemetry.io/sdk/trace"
+)
+
+// Pipeline is a TracerProvider together with the exporters its span
+// processors send spans to.
+type Pipeline struct {
+	// Provider is the configured TracerProvider. Use its Register method
+	// to install it globally.
+	Provider *trace.TracerProvider
+
+	flushers []func()
+	closers  []func() error
+	stop     func()
+	stopOnce sync.Once
+}
+
+// ForceFlush exports the spans queued by the span processors and flushes
+// the exporters. It returns the error of the context if the context is done
+// first.
+func (p *Pipeline) ForceFlush(ctx context.Context) error {
+	return p.Provider.ForceFlush(ctx)
+}
+
+// Shutdown stops reloading the configuration, shuts down all span
+// processors of the provider, which exports the spans still queued, and
+// then flushes and closes the exporters.
+func (p *Pipeline) Shutdown() {
+	p.stopOnce.Do(func() {
+		if p.stop != nil {
+			p.stop()
+		}
+	})
+	p.Provider.Shutdown()
+	for _, flush := range p.flushers {
+		flush()
+	}
+	for _, c := range p.closers {
+		_ = c()
+	}
+	p.flushers, p.closers = nil, nil
+}
+
+// detectResource returns the resource detected by the detectors. The error
+// of a failing detector is passed to onError, or logged if onError is nil.
+func detectResource(detectors []resource.Detector, onError func(err error)) *resource.Resource {
+	res, err := resource.Detect(context.Background(), detectors...)
+	if err == nil {
+		return res
+	}
+	if onError != nil {
+		onError(fmt.Errorf("detecting resource: %v", err))
+	} else {
+		log.Printf("Error when detecting resource: %v", err)
+	}
+	return res
+}
+
+// addStdout registers a stdout exporter with a simple or batch span
+// processor.
+func (p *Pipeline) addStdout(o stdout.Options, batch bool, bo trace.BatchSpanProcessorOptions) error {
+	e, err := stdout.NewExporter(o)
+	if err != nil {
+		return err
+	}
+	return p.addExporter(e, batch, bo)
+}
+
+// addJaeger registers a Jaeger exporter with a simple or batch span
+// processor. The exporter is flushed on Shutdown.
+func (p *Pipeline) addJaeger(o jaeger.Options, batch bool, bo trace.BatchSpanProcessorOptions) error {
+	e, err := jaeger.NewExporter(o)
+	if err != nil {
+		return err
+	}
+	p.flushers = append(p.flushers, e.Flush)
+	return p.addExporter(e, batch, bo)
+}
+
+// addFile registers a file exporter with a simple or batch span processor.
+// The exporter is closed on Shutdown.
+func (p *Pipeline) addFile(o file.Options, batch bool, bo trace.BatchSpanProcessorOptions) error {
+	e, err := file.NewExporter(o)
+	if err != nil {
+		return err
+	}
+	p.closers = append(p.closers, e.Close)
+	return p.addExporter(e, batch, bo)
+}
+
+func (p *Pipeline) addExporter(e trace.Exporter, batch bool, bo trace.BatchSpanProcessorOptions) error {
+	if !batch {
+		p.Provider.RegisterSpanProcessor(trace.NewSimpleSpanProcessor(e))
+		return nil
+	}
+	bsp, err := trace.NewBatchSpanProcessorFromBatcher(
+		trace.BatcherFromSyncer(trace.SyncerFromExporter(e)), batchOptions(bo)...)
+	if err != nil {
+		return err
+	}
+	p.Provider.RegisterSpanProcessor(bsp)
+	return nil
+}
+
+// batchOptions converts the non-zero fields of o to options.
+func batchOptions(o trace.BatchSpanProcessorOptions) []trace.BatchSpanProcessorOption {
+	var opts []trace.BatchSpanProcessorOption
+	if o.ScheduledDelayMillis > 0 {
+		opts = append(opts, trace.WithScheduleDelayMillis(o.ScheduledDelayMillis))
+	}
+	if o.MaxQueueSize > 0 {
+		opts = append(opts, trace.WithMaxQueueSize(o.MaxQueueSize))
+	}
+	if o.MaxExportBatchSize > 0 {
+		opts = append(opts, trace.WithMaxExportBatchSize(o.MaxExportBatchSize))
+	}
+	if o.ExportTimeout > 0 {
+		opts = append(opts, trace.WithExportTimeout(o.ExportTimeout))
+	}
+	return opts
+}
+
+// newSampler returns the sampler with the given name: always_on,
+// always_off, traceidratio or one of them prefixed with parentbased_, which
+// wraps the sampler with trace.ParentBased. The traceidratio sampler
+// samples the fraction arg of traces, or all traces if arg is nil.
+func newSampler(name string, arg *float64) (trace.Sampler, error) {
+	if root := strings.TrimPrefix(name, "parentbased_"); root != name {
+		s, err := newSampler(root, arg)
+		if err != nil {
+			return nil, err
+		}
+		return trace.ParentBased(s), nil
+	}
+	switch name {
+	case "always_on":
+		return trace.AlwaysSample(), nil
+	case "always_off":
+		return trace.NeverSample(), nil
+	case "traceidratio":
+		if arg == nil {
+			return trace.AlwaysSample(), nil
+		}
+		if !(*arg >= 0 && *arg <= 1) {
+			return nil, errors.New("must be between 0 and 1")
+		}
+		return trace.ProbabilitySampler(*arg), nil
+	}
+	return nil, fmt.Errorf("unknown sampler %q", name)
+}
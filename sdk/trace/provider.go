@@ -0,0 +1,300 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	apitrace "go.opentelemetry.io/api/trace"
+)
+
+// TracerProviderOptions are the options used to create a TracerProvider.
+type TracerProviderOptions struct {
+	config     Config
+	processors []SpanProcessor
+}
+
+// TracerProviderOption configures a TracerProvider.
+type TracerProviderOption func(*TracerProviderOptions)
+
+// TracerProvider owns a tracing configuration, an ID generator and a set of
+// span processors, and hands out tracers that use them. Tracers of
+// different providers are fully independent of each other.
+//
+// The package level functions ApplyConfig, RegisterSpanProcessor,
+// UnregisterSpanProcessor, RegisterExporter, UnregisterExporter and
+// Register operate on a default TracerProvider.
+type TracerProvider struct {
+	mu             sync.Mutex   // serializes writes of the fields below
+	config         atomic.Value // access atomically, holds *Config
+	spanProcessors atomic.Value // access atomically, holds spanProcessorMap
+	exporters      atomic.Value // access atomically, holds exportersMap
+	tracer         *tracer
+	tracers        map[instrumentation]*tracer // protected by mu
+	registerOnce   sync.Once
+}
+
+// instrumentation identifies the instrumentation library of a named tracer.
+type instrumentation struct {
+	name, version string
+}
+
+var defaultProvider = NewTracerProvider()
+
+// NewTracerProvider creates a TracerProvider with the default configuration
+// updated by the given options. If no IDGenerator is configured, the
+// provider gets its own randomly seeded generator.
+func NewTracerProvider(opts ...TracerProviderOption) *TracerProvider {
+	o := &TracerProviderOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	p := &TracerProvider{
+		tracers: make(map[instrumentation]*tracer),
+	}
+	p.tracer = &tracer{provider: p}
+	c := defaultConfig()
+	c.IDGenerator = newDefaultIDGenerator()
+	p.config.Store(&c)
+	p.ApplyConfig(o.config)
+	for _, sp := range o.processors {
+		p.RegisterSpanProcessor(sp)
+	}
+	return p
+}
+
+// WithConfig sets the configuration of the provider. Fields that are not
+// set keep their default values.
+func WithConfig(cfg Config) TracerProviderOption {
+	return func(o *TracerProviderOptions) {
+		o.config = cfg
+	}
+}
+
+// WithSpanProcessor registers the span processor with the provider.
+func WithSpanProcessor(sp SpanProcessor) TracerProviderOption {
+	return func(o *TracerProviderOptions) {
+		o.processors = append(o.processors, sp)
+	}
+}
+
+// WithSyncer registers the exporter with the provider using a
+// SimpleSpanProcessor.
+func WithSyncer(e Exporter) TracerProviderOption {
+	return WithSpanProcessor(NewSimpleSpanProcessor(e))
+}
+
+var _ apitrace.Provider = (*TracerProvider)(nil)
+
+// Tracer returns the anonymous tracer of the provider.
+func (p *TracerProvider) Tracer() apitrace.Tracer {
+	return p.tracer
+}
+
+// GetTracer returns the tracer of the instrumentation library identified by
+// name and version. Spans started by the tracer record name and version in
+// SpanData. Repeated calls with the same arguments return the same tracer.
+func (p *TracerProvider) GetTracer(name, version string) apitrace.Tracer {
+	if name == "" && version == "" {
+		return p.tracer
+	}
+	key := instrumentation{name: name, version: version}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	t, ok := p.tracers[key]
+	if !ok {
+		t = &tracer{provider: p, name: name, version: version}
+		p.tracers[key] = t
+	}
+	return t
+}
+
+// Register installs the provider as the global provider and its anonymous
+// tracer as the global tracer. It only executes once per provider and
+// returns the installed tracer.
+func (p *TracerProvider) Register() apitrace.Tracer {
+	p.registerOnce.Do(func() {
+		apitrace.SetGlobalTracer(p.tracer)
+		apitrace.SetGlobalProvider(p)
+	})
+	return p.tracer
+}
+
+// ApplyConfig applies changes to the configuration of the provider.
+//
+// Fields not provided in the given config are going to be preserved.
+func (p *TracerProvider) ApplyConfig(cfg Config) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	c := *p.config.Load().(*Config)
+	mergeConfig(&c, cfg)
+	p.config.Store(&c)
+}
+
+// ResetConfig replaces the configuration of the provider. Unlike
+// ApplyConfig, fields not provided in the given config get their default
+// values, except for the IDGenerator, which is preserved.
+func (p *TracerProvider) ResetConfig(cfg Config) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	c := defaultConfig()
+	c.IDGenerator = p.config.Load().(*Config).IDGenerator
+	mergeConfig(&c, cfg)
+	p.config.Store(&c)
+}
+
+// defaultConfig returns the configuration of a new provider without an
+// IDGenerator.
+func defaultConfig() Config {
+	return Config{
+		DefaultSampler:        ParentBased(ProbabilitySampler(defaultSamplingProbability)),
+		MaxAttributesPerSpan:  DefaultMaxAttributesPerSpan,
+		MaxEventsPerSpan:      DefaultMaxEventsPerSpan,
+		MaxLinksPerSpan:       DefaultMaxLinksPerSpan,
+		MaxAttributesPerEvent: DefaultMaxAttributesPerEvent,
+		MaxAttributesPerLink:  DefaultMaxAttributesPerLink,
+	}
+}
+
+// mergeConfig sets the fields of c that are provided in cfg.
+func mergeConfig(c *Config, cfg Config) {
+	if cfg.DefaultSampler != nil {
+		c.DefaultSampler = cfg.DefaultSampler
+	}
+	if cfg.IDGenerator != nil {
+		c.IDGenerator = cfg.IDGenerator
+	}
+	if cfg.MaxEventsPerSpan > 0 {
+		c.MaxEventsPerSpan = cfg.MaxEventsPerSpan
+	}
+	if cfg.MaxAttributesPerSpan > 0 {
+		c.MaxAttributesPerSpan = cfg.MaxAttributesPerSpan
+	}
+	if cfg.MaxLinksPerSpan > 0 {
+		c.MaxLinksPerSpan = cfg.MaxLinksPerSpan
+	}
+	if cfg.MaxAttributesPerEvent > 0 {
+		c.MaxAttributesPerEvent = cfg.MaxAttributesPerEvent
+	}
+	if cfg.MaxAttributesPerLink > 0 {
+		c.MaxAttributesPerLink = cfg.MaxAttributesPerLink
+	}
+	if cfg.MaxAttributeValueLength > 0 {
+		c.MaxAttributeValueLength = cfg.MaxAttributeValueLength
+	}
+	if cfg.MaxAttributeValueLengthPerKey != nil {
+		c.MaxAttributeValueLengthPerKey = cfg.MaxAttributeValueLengthPerKey
+	}
+	if cfg.Resource != nil {
+		c.Resource = cfg.Resource
+	}
+}
+
+// RegisterSpanProcessor adds to the list of SpanProcessors of the provider.
+func (p *TracerProvider) RegisterSpanProcessor(s SpanProcessor) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	new := make(spanProcessorMap)
+	if old, ok := p.spanProcessors.Load().(spanProcessorMap); ok {
+		for k, v := range old {
+			new[k] = v
+		}
+	}
+	new[s] = struct{}{}
+	p.spanProcessors.Store(new)
+}
+
+// UnregisterSpanProcessor removes the SpanProcessor from the list of
+// SpanProcessors of the provider and shuts it down.
+func (p *TracerProvider) UnregisterSpanProcessor(s SpanProcessor) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	new := make(spanProcessorMap)
+	if old, ok := p.spanProcessors.Load().(spanProcessorMap); ok {
+		for k, v := range old {
+			new[k] = v
+		}
+	}
+	delete(new, s)
+	p.spanProcessors.Store(new)
+	s.Shutdown()
+}
+
+// RegisterExporter adds to the list of Exporters of the provider that will
+// receive sampled trace spans.
+// TODO(rghetia) : Remove it.
+func (p *TracerProvider) RegisterExporter(e Exporter) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	new := make(exportersMap)
+	if old, ok := p.exporters.Load().(exportersMap); ok {
+		for k, v := range old {
+			new[k] = v
+		}
+	}
+	new[e] = struct{}{}
+	p.exporters.Store(new)
+}
+
+// UnregisterExporter removes the Exporter from the list of Exporters of the
+// provider.
+// TODO(rghetia) : Remove it.
+func (p *TracerProvider) UnregisterExporter(e Exporter) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	new := make(exportersMap)
+	if old, ok := p.exporters.Load().(exportersMap); ok {
+		for k, v := range old {
+			new[k] = v
+		}
+	}
+	delete(new, e)
+	p.exporters.Store(new)
+}
+
+// ForceFlush flushes all span processors of the provider and the
+// registered exporters that implement Flusher. It returns the first error,
+// for example the error of the context if it is done before all spans were
+// exported.
+func (p *TracerProvider) ForceFlush(ctx context.Context) error {
+	var firstErr error
+	sps, _ := p.spanProcessors.Load().(spanProcessorMap)
+	for sp := range sps {
+		if err := sp.ForceFlush(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	exp, _ := p.exporters.Load().(exportersMap)
+	for e := range exp {
+		if err := flushExporter(ctx, e); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Shutdown unregisters and shuts down all span processors of the provider.
+func (p *TracerProvider) Shutdown() {
+	sps, _ := p.spanProcessors.Load().(spanProcessorMap)
+	for sp := range sps {
+		p.UnregisterSpanProcessor(sp)
+	}
+}
+
+func (p *TracerProvider) getConfig() *Config {
+	return p.config.Load().(*Config)
+}
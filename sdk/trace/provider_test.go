@@ -0,0 +1,273 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"go.opentelemetry.io/api/core"
+	"go.opentelemetry.io/api/key"
+	apitrace "go.opentelemetry.io/api/trace"
+	"go.opentelemetry.io/sdk/resource"
+	sdktrace "go.opentelemetry.io/sdk/trace"
+	"go.opentelemetry.io/sdk/trace/idgen"
+)
+
+func TestTracerProvidersAreIndependent(t *testing.T) {
+	sp1 := NewTestSpanProcessor()
+	p1 := sdktrace.NewTracerProvider(
+		sdktrace.WithConfig(sdktrace.Config{DefaultSampler: sdktrace.AlwaysSample()}),
+		sdktrace.WithSpanProcessor(sp1),
+	)
+	sp2 := NewTestSpanProcessor()
+	p2 := sdktrace.NewTracerProvider(
+		sdktrace.WithConfig(sdktrace.Config{DefaultSampler: sdktrace.NeverSample()}),
+		sdktrace.WithSpanProcessor(sp2),
+	)
+
+	_, span1 := p1.Tracer().Start(context.Background(), "span1")
+	span1.End()
+	_, span2 := p2.Tracer().Start(context.Background(), "span2")
+	span2.End()
+
+	if !span1.SpanContext().IsSampled() {
+		t.Error("span of always sampling provider is not sampled")
+	}
+	if span2.SpanContext().IsSampled() {
+		t.Error("span of never sampling provider is sampled")
+	}
+	if got := len(sp1.spansEnded); got != 1 {
+		t.Errorf("provider 1 processor: got %d ended spans, want 1", got)
+	}
+	if got := len(sp2.spansEnded); got != 0 {
+		t.Errorf("provider 2 processor: got %d ended spans, want 0", got)
+	}
+}
+
+func TestTracerProviderChildSpansUseParentProvider(t *testing.T) {
+	sp := NewTestSpanProcessor()
+	p := sdktrace.NewTracerProvider(
+		sdktrace.WithConfig(sdktrace.Config{DefaultSampler: sdktrace.AlwaysSample()}),
+		sdktrace.WithSpanProcessor(sp),
+	)
+	ctx, parent := p.Tracer().Start(context.Background(), "parent")
+	_, child := parent.Tracer().Start(ctx, "child")
+	child.End()
+	parent.End()
+
+	if got := len(sp.spansEnded); got != 2 {
+		t.Fatalf("got %d ended spans, want 2", got)
+	}
+	if got, want := sp.spansEnded[0].ParentSpanID, parent.SpanContext().SpanID; got != want {
+		t.Errorf("child parent span ID: got %x, want %x", got, want)
+	}
+}
+
+func TestTracerProviderShutdown(t *testing.T) {
+	sp := NewTestSpanProcessor()
+	p := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sp))
+	p.Shutdown()
+	if sp.shutdownCount != 1 {
+		t.Errorf("processor shutdown count: got %d, want 1", sp.shutdownCount)
+	}
+
+	_, span := p.Tracer().Start(context.Background(), "after shutdown", apitrace.WithRecordEvents())
+	span.End()
+	if got := len(sp.spansEnded); got != 0 {
+		t.Errorf("got %d ended spans after shutdown, want 0", got)
+	}
+}
+
+func TestTracerProviderGetTracer(t *testing.T) {
+	sp := NewTestSpanProcessor()
+	var sampled []string
+	p := sdktrace.NewTracerProvider(
+		sdktrace.WithConfig(sdktrace.Config{
+			DefaultSampler: sdktrace.SamplerFunc(func(sp sdktrace.SamplingParameters) sdktrace.SamplingDecision {
+				sampled = append(sampled, sp.TracerName+"@"+sp.TracerVersion)
+				return sdktrace.SamplingDecision{Result: apitrace.RecordAndSample}
+			}),
+		}),
+		sdktrace.WithSpanProcessor(sp),
+	)
+
+	tr := p.GetTracer("go.opentelemetry.io/test", "1.0")
+	if tr != p.GetTracer("go.opentelemetry.io/test", "1.0") {
+		t.Error("GetTracer returned different tracers for the same library")
+	}
+	if tr == p.GetTracer("go.opentelemetry.io/test", "2.0") {
+		t.Error("GetTracer returned the same tracer for different versions")
+	}
+	if p.GetTracer("", "") != p.Tracer() {
+		t.Error("GetTracer without name did not return the anonymous tracer")
+	}
+
+	_, span := tr.Start(context.Background(), "named")
+	span.End()
+	if got := len(sp.spansEnded); got != 1 {
+		t.Fatalf("got %d ended spans, want 1", got)
+	}
+	sd := sp.spansEnded[0]
+	if sd.TracerName != "go.opentelemetry.io/test" || sd.TracerVersion != "1.0" {
+		t.Errorf("tracer of span: got %q@%q", sd.TracerName, sd.TracerVersion)
+	}
+	if len(sampled) != 1 || sampled[0] != "go.opentelemetry.io/test@1.0" {
+		t.Errorf("tracer passed to sampler: got %v", sampled)
+	}
+}
+
+func TestTracerProviderRegister(t *testing.T) {
+	p := sdktrace.NewTracerProvider()
+	oldTracer, oldProvider := apitrace.GlobalTracer(), apitrace.GlobalProvider()
+	defer func() {
+		apitrace.SetGlobalTracer(oldTracer)
+		apitrace.SetGlobalProvider(oldProvider)
+	}()
+	p.Register()
+	if apitrace.GlobalProvider() != apitrace.Provider(p) {
+		t.Error("Register did not install the global provider")
+	}
+	if apitrace.GlobalTracer() != p.Tracer() {
+		t.Error("Register did not install the global tracer")
+	}
+}
+
+func TestTracerProviderResource(t *testing.T) {
+	sp := NewTestSpanProcessor()
+	res := resource.New(resource.ServiceName.String("checkout"), resource.HostName.String("host1"))
+	p := sdktrace.NewTracerProvider(
+		sdktrace.WithConfig(sdktrace.Config{
+			DefaultSampler: sdktrace.AlwaysSample(),
+			Resource:       res,
+		}),
+		sdktrace.WithSpanProcessor(sp),
+	)
+
+	_, span := p.Tracer().Start(context.Background(), "configured")
+	span.End()
+	type resourcer interface {
+		WithResources(...core.KeyValue) apitrace.Tracer
+	}
+	tr := p.Tracer().(resourcer).WithResources(
+		resource.HostName.String("host2"),
+		key.New("component").String("db"),
+	)
+	_, span = tr.Start(context.Background(), "merged")
+	span.End()
+
+	if got := len(sp.spansEnded); got != 2 {
+		t.Fatalf("got %d ended spans, want 2", got)
+	}
+	if sp.spansEnded[0].Resource != res {
+		t.Errorf("resource of span: got %v, want configured resource", sp.spansEnded[0].Resource.Attributes())
+	}
+	want := []core.KeyValue{
+		key.New("component").String("db"),
+		resource.HostName.String("host2"),
+		resource.ServiceName.String("checkout"),
+	}
+	if diff := cmp.Diff(sp.spansEnded[1].Resource.Attributes(), want); diff != "" {
+		t.Errorf("resource of span with tracer resources: -got +want %s", diff)
+	}
+}
+
+func TestTracerWithServiceAndComponent(t *testing.T) {
+	sp := NewTestSpanProcessor()
+	p := sdktrace.NewTracerProvider(
+		sdktrace.WithConfig(sdktrace.Config{
+			DefaultSampler: sdktrace.AlwaysSample(),
+			Resource:       resource.New(resource.ServiceName.String("checkout"), resource.HostName.String("host1")),
+		}),
+		sdktrace.WithSpanProcessor(sp),
+	)
+	type identifier interface {
+		WithService(string) apitrace.Tracer
+		WithComponent(string) apitrace.Tracer
+		WithResources(...core.KeyValue) apitrace.Tracer
+	}
+	tr := p.Tracer().(identifier).WithService("payment").(identifier).WithComponent("database/sql")
+	tr = tr.(identifier).WithResources(resource.ServiceName.String("ignored"), resource.HostName.String("host2"))
+	_, span := tr.Start(context.Background(), "query")
+	span.End()
+
+	if got := len(sp.spansEnded); got != 1 {
+		t.Fatalf("got %d ended spans, want 1", got)
+	}
+	sd := sp.spansEnded[0]
+	if sd.TracerName != "database/sql" {
+		t.Errorf("tracer name of span: got %q, want %q", sd.TracerName, "database/sql")
+	}
+	want := []core.KeyValue{
+		resource.HostName.String("host2"),
+		resource.ServiceName.String("payment"),
+	}
+	if diff := cmp.Diff(sd.Resource.Attributes(), want); diff != "" {
+		t.Errorf("resource of span: -got +want %s", diff)
+	}
+}
+
+func TestTracerProviderIDGenerator(t *testing.T) {
+	p := sdktrace.NewTracerProvider(sdktrace.WithConfig(sdktrace.Config{
+		DefaultSampler: sdktrace.AlwaysSample(),
+		IDGenerator:    idgen.NewSequential(),
+	}))
+	ctx, parent := p.Tracer().Start(context.Background(), "parent")
+	_, child := p.Tracer().Start(ctx, "child")
+	want := []core.SpanContext{
+		{TraceID: core.TraceID{Low: 1}, SpanID: 1, TraceFlags: core.TraceFlagsSampled},
+		{TraceID: core.TraceID{Low: 1}, SpanID: 2, TraceFlags: core.TraceFlagsSampled},
+	}
+	for i, span := range []apitrace.Span{parent, child} {
+		if got := span.SpanContext(); got != want[i] {
+			t.Errorf("span context of span %d: got %v, want %v", i, got, want[i])
+		}
+	}
+}
+
+func TestTracerProviderResetConfig(t *testing.T) {
+	sp := NewTestSpanProcessor()
+	p := sdktrace.NewTracerProvider(
+		sdktrace.WithConfig(sdktrace.Config{
+			DefaultSampler:       sdktrace.AlwaysSample(),
+			IDGenerator:          idgen.NewSequential(),
+			MaxAttributesPerSpan: 1,
+		}),
+		sdktrace.WithSpanProcessor(sp),
+	)
+	attrs := []core.KeyValue{key.New("a").Int(1), key.New("b").Int(2)}
+	_, span := p.Tracer().Start(context.Background(), "limited", apitrace.WithAttributes(attrs...))
+	span.End()
+
+	// The attribute limit gets its default value, the ID generator is kept.
+	p.ResetConfig(sdktrace.Config{DefaultSampler: sdktrace.AlwaysSample()})
+	_, span = p.Tracer().Start(context.Background(), "reset", apitrace.WithAttributes(attrs...))
+	span.End()
+
+	if got := len(sp.spansEnded); got != 2 {
+		t.Fatalf("got %d ended spans, want 2", got)
+	}
+	if got := len(sp.spansEnded[0].Attributes); got != 1 {
+		t.Errorf("attributes of span before reset: got %d, want 1", got)
+	}
+	if got := len(sp.spansEnded[1].Attributes); got != 2 {
+		t.Errorf("attributes of span after reset: got %d, want 2", got)
+	}
+	if got, want := sp.spansEnded[1].SpanContext.TraceID, (core.TraceID{Low: 2}); got != want {
+		t.Errorf("trace ID after reset: got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/api/core"
+	"go.opentelemetry.io/api/key"
+)
+
+// Attribute keys describing the decision of a sampler, as used by Jaeger.
+var (
+	SamplerTypeKey  = key.New("sampler.type")
+	SamplerParamKey = key.New("sampler.param")
+)
+
+// Values of SamplerTypeKey.
+const (
+	SamplerTypeRateLimiting  = "ratelimiting"
+	SamplerTypeProbabilistic = "probabilistic"
+)
+
+// RateLimitingSamplerOption configures a sampler created by
+// RateLimitingSampler.
+type RateLimitingSamplerOption func(*rateLimiter)
+
+// WithProbabilityFloor samples spans that exceed the rate limit with the
+// given probability, so that a fraction of the traffic is sampled even
+// when the limit is much lower than the rate of new traces.
+func WithProbabilityFloor(fraction float64) RateLimitingSamplerOption {
+	return func(rl *rateLimiter) {
+		rl.floor = ProbabilitySampler(fraction)
+		rl.floorAttrs = []core.KeyValue{
+			SamplerTypeKey.String(SamplerTypeProbabilistic),
+			SamplerParamKey.Float64(fraction),
+		}
+	}
+}
+
+// RateLimitingSampler returns a Sampler that samples at most
+// tracesPerSecond spans per second, allowing bursts of up to one second
+// worth of spans. It counts every span it is consulted for, so it is
+// usually wrapped with ParentBased to limit the number of new traces.
+// If tracesPerSecond is zero or negative, no spans are sampled by the rate
+// limit.
+//
+// The decision carries SamplerTypeKey and SamplerParamKey attributes
+// naming the rule that sampled the span.
+func RateLimitingSampler(tracesPerSecond float64, opts ...RateLimitingSamplerOption) Sampler {
+	return newRateLimitingSampler(tracesPerSecond, time.Now, opts...)
+}
+
+func newRateLimitingSampler(tracesPerSecond float64, now func() time.Time, opts ...RateLimitingSamplerOption) Sampler {
+	rate, capacity := tracesPerSecond, tracesPerSecond
+	switch {
+	case rate <= 0:
+		rate, capacity = 0, 0
+	case capacity < 1:
+		capacity = 1
+	}
+	rl := &rateLimiter{
+		rate:     rate,
+		capacity: capacity,
+		tokens:   capacity,
+		now:      now,
+		last:     now(),
+		attrs: []core.KeyValue{
+			SamplerTypeKey.String(SamplerTypeRateLimiting),
+			SamplerParamKey.Float64(tracesPerSecond),
+		},
+	}
+	for _, opt := range opts {
+		opt(rl)
+	}
+	return &describedSampler{
+		description: fmt.Sprintf("RateLimitingSampler{%g}", tracesPerSecond),
+		sample:      rl.sample,
+	}
+}
+
+// rateLimiter is a token bucket holding up to capacity tokens, which is
+// refilled with rate tokens per second.
+type rateLimiter struct {
+	rate       float64
+	capacity   float64
+	attrs      []core.KeyValue
+	floor      Sampler
+	floorAttrs []core.KeyValue
+	now        func() time.Time
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func (rl *rateLimiter) sample(p SamplingParameters) SamplingDecision {
+	if rl.take() {
+		return sampled(true, rl.attrs...)
+	}
+	if rl.floor != nil && rl.floor.ShouldSample(p).Sampled() {
+		return sampled(true, rl.floorAttrs...)
+	}
+	return sampled(false)
+}
+
+// take removes a token from the bucket and reports whether there was one.
+func (rl *rateLimiter) take() bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	now := rl.now()
+	if elapsed := now.Sub(rl.last); elapsed > 0 {
+		rl.tokens += elapsed.Seconds() * rl.rate
+		if rl.tokens > rl.capacity {
+			rl.tokens = rl.capacity
+		}
+	}
+	rl.last = now
+	if rl.tokens < 1 {
+		return false
+	}
+	rl.tokens--
+	return true
+}
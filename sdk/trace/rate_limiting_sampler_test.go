@@ -0,0 +1,158 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"go.opentelemetry.io/api/core"
+	apitrace "go.opentelemetry.io/api/trace"
+)
+
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func countSampled(s Sampler, n int) int {
+	sampled := 0
+	for i := 0; i < n; i++ {
+		if s.ShouldSample(SamplingParameters{TraceID: core.TraceID{High: ^uint64(0)}}).Sampled() {
+			sampled++
+		}
+	}
+	return sampled
+}
+
+func TestRateLimitingSampler(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1569931200, 0)}
+	s := newRateLimitingSampler(10, clock.now)
+
+	if got, want := countSampled(s, 100), 10; got != want {
+		t.Errorf("initial burst: got %d sampled, want %d", got, want)
+	}
+	clock.advance(500 * time.Millisecond)
+	if got, want := countSampled(s, 100), 5; got != want {
+		t.Errorf("after 500ms: got %d sampled, want %d", got, want)
+	}
+	// The bucket holds at most one second worth of tokens.
+	clock.advance(time.Hour)
+	if got, want := countSampled(s, 100), 10; got != want {
+		t.Errorf("after an hour: got %d sampled, want %d", got, want)
+	}
+	clock.advance(-time.Minute)
+	if got, want := countSampled(s, 100), 0; got != want {
+		t.Errorf("after clock went backwards: got %d sampled, want %d", got, want)
+	}
+}
+
+func TestRateLimitingSamplerFractionalRate(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1569931200, 0)}
+	s := newRateLimitingSampler(0.5, clock.now)
+
+	if got, want := countSampled(s, 10), 1; got != want {
+		t.Errorf("initial burst: got %d sampled, want %d", got, want)
+	}
+	clock.advance(time.Second)
+	if got, want := countSampled(s, 10), 0; got != want {
+		t.Errorf("after 1s: got %d sampled, want %d", got, want)
+	}
+	clock.advance(time.Second)
+	if got, want := countSampled(s, 10), 1; got != want {
+		t.Errorf("after 2s: got %d sampled, want %d", got, want)
+	}
+}
+
+func TestRateLimitingSamplerNonPositiveRate(t *testing.T) {
+	for _, rate := range []float64{0, -1} {
+		clock := &fakeClock{t: time.Unix(1569931200, 0)}
+		s := newRateLimitingSampler(rate, clock.now)
+
+		if got := countSampled(s, 10); got != 0 {
+			t.Errorf("rate %g: got %d sampled, want 0", rate, got)
+		}
+		clock.advance(time.Hour)
+		if got := countSampled(s, 10); got != 0 {
+			t.Errorf("rate %g after an hour: got %d sampled, want 0", rate, got)
+		}
+	}
+}
+
+func TestRateLimitingSamplerAttributes(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1569931200, 0)}
+	s := newRateLimitingSampler(1, clock.now, WithProbabilityFloor(0.5))
+
+	low := SamplingParameters{TraceID: core.TraceID{High: 1}}
+	high := SamplingParameters{TraceID: core.TraceID{High: ^uint64(0)}}
+	for _, tc := range []struct {
+		name   string
+		params SamplingParameters
+		want   SamplingDecision
+	}{
+		{
+			name:   "rate limit",
+			params: high,
+			want: SamplingDecision{Result: apitrace.RecordAndSample, Attributes: []core.KeyValue{
+				SamplerTypeKey.String(SamplerTypeRateLimiting),
+				SamplerParamKey.Float64(1),
+			}},
+		},
+		{
+			name:   "probability floor",
+			params: low,
+			want: SamplingDecision{Result: apitrace.RecordAndSample, Attributes: []core.KeyValue{
+				SamplerTypeKey.String(SamplerTypeProbabilistic),
+				SamplerParamKey.Float64(0.5),
+			}},
+		},
+		{
+			name:   "not sampled",
+			params: high,
+			want:   SamplingDecision{Result: apitrace.Drop},
+		},
+	} {
+		if diff := cmp.Diff(s.ShouldSample(tc.params), tc.want); diff != "" {
+			t.Errorf("%s: -got +want %s", tc.name, diff)
+		}
+	}
+}
+
+func TestSamplingDecisionAttributes(t *testing.T) {
+	clock := &fakeClock{t: time.Unix(1569931200, 0)}
+	var te testExporter
+	p := NewTracerProvider(
+		WithConfig(Config{DefaultSampler: newRateLimitingSampler(1, clock.now)}),
+		WithSyncer(&te),
+	)
+	_, span := p.Tracer().Start(context.Background(), "span")
+	span.End()
+	if len(te.spans) != 1 {
+		t.Fatalf("got %d exported spans, want 1", len(te.spans))
+	}
+	want := []core.KeyValue{
+		SamplerTypeKey.String(SamplerTypeRateLimiting),
+		SamplerParamKey.Float64(1),
+	}
+	if diff := cmp.Diff(te.spans[0].Attributes, want); diff != "" {
+		t.Errorf("span attributes: -got +want %s", diff)
+	}
+}
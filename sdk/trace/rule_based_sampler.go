@@ -0,0 +1,130 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/api/core"
+)
+
+// NameMatcher reports whether a span name is matched by a Rule.
+type NameMatcher func(name string) bool
+
+// ExactName matches the span name s.
+func ExactName(s string) NameMatcher {
+	return func(name string) bool {
+		return name == s
+	}
+}
+
+// NamePrefix matches span names starting with prefix.
+func NamePrefix(prefix string) NameMatcher {
+	return func(name string) bool {
+		return strings.HasPrefix(name, prefix)
+	}
+}
+
+// NameRegexp matches span names matched by re.
+func NameRegexp(re *regexp.Regexp) NameMatcher {
+	return re.MatchString
+}
+
+// Rule selects spans by their name and start attributes and delegates the
+// sampling decision for them to a Sampler.
+type Rule struct {
+	// Name matches the span name. If nil, all names are matched.
+	Name NameMatcher
+
+	// Attributes must all be among the start attributes of the span, with
+	// equal values. If empty, all spans are matched.
+	Attributes []core.KeyValue
+
+	// Sampler decides whether a matched span is sampled.
+	Sampler Sampler
+}
+
+func (r *Rule) matches(p SamplingParameters) bool {
+	if r.Name != nil && !r.Name(p.Name) {
+		return false
+	}
+	for _, want := range r.Attributes {
+		if !hasAttribute(p.Attributes, want) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasAttribute(kvs []core.KeyValue, want core.KeyValue) bool {
+	for _, kv := range kvs {
+		if kv.Key == want.Key && valuesEqual(kv.Value, want.Value) {
+			return true
+		}
+	}
+	return false
+}
+
+func valuesEqual(a, b core.Value) bool {
+	if a.Type != b.Type {
+		return false
+	}
+	if a.Type == core.BYTES {
+		return bytes.Equal(a.Bytes, b.Bytes)
+	}
+	return a.Emit() == b.Emit()
+}
+
+// RuleBasedSampler returns a Sampler that delegates the decision to the
+// sampler of the first rule matching the span, or to defaultSampler if no
+// rule matches. For example, the sampler
+//
+//	RuleBasedSampler([]Rule{
+//		{Name: ExactName("/checkout"), Sampler: AlwaysSample()},
+//		{Name: NamePrefix("/healthz"), Sampler: NeverSample()},
+//	}, RateLimitingSampler(10))
+//
+// samples all checkouts, no health checks and at most 10 other spans per
+// second.
+//
+// RuleBasedSampler panics if defaultSampler or the Sampler of a rule is nil.
+func RuleBasedSampler(rules []Rule, defaultSampler Sampler) Sampler {
+	if defaultSampler == nil {
+		panic("trace: RuleBasedSampler with nil default sampler")
+	}
+	rules = append([]Rule(nil), rules...)
+	descriptions := make([]string, len(rules))
+	for i, r := range rules {
+		if r.Sampler == nil {
+			panic(fmt.Sprintf("trace: RuleBasedSampler rule %d has nil Sampler", i))
+		}
+		descriptions[i] = r.Sampler.Description()
+	}
+	return &describedSampler{
+		description: fmt.Sprintf("RuleBasedSampler{rules:[%s],default:%s}",
+			strings.Join(descriptions, ","), defaultSampler.Description()),
+		sample: func(p SamplingParameters) SamplingDecision {
+			for i := range rules {
+				if rules[i].matches(p) {
+					return rules[i].Sampler.ShouldSample(p)
+				}
+			}
+			return defaultSampler.ShouldSample(p)
+		},
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"go.opentelemetry.io/api/core"
+	"go.opentelemetry.io/api/key"
+	apitrace "go.opentelemetry.io/api/trace"
+)
+
+func TestRuleBasedSampler(t *testing.T) {
+	var called string
+	s := RuleBasedSampler([]Rule{
+		{Name: ExactName("/checkout"), Sampler: named("checkout", &called, true)},
+		{Name: NamePrefix("/healthz"), Sampler: named("healthz", &called, false)},
+		{
+			Name:       NameRegexp(regexp.MustCompile(`^/api/v\d+/`)),
+			Attributes: []core.KeyValue{key.New("http.method").String("POST")},
+			Sampler:    named("api post", &called, true),
+		},
+		{
+			Attributes: []core.KeyValue{
+				key.New("debug").Bool(true),
+				key.New("payload").Bytes([]byte{1, 2}),
+			},
+			Sampler: named("debug", &called, true),
+		},
+	}, named("default", &called, false))
+
+	for _, tc := range []struct {
+		name  string
+		attrs []core.KeyValue
+		want  string
+	}{
+		{name: "/checkout", want: "checkout"},
+		{name: "/checkout/confirm", want: "default"},
+		{name: "/healthz/ready", want: "healthz"},
+		{name: "/api/v2/orders", attrs: []core.KeyValue{key.New("http.method").String("POST")}, want: "api post"},
+		{name: "/api/v2/orders", attrs: []core.KeyValue{key.New("http.method").String("GET")}, want: "default"},
+		{name: "/api/orders", attrs: []core.KeyValue{key.New("http.method").String("POST")}, want: "default"},
+		{
+			name: "/checkout",
+			attrs: []core.KeyValue{
+				key.New("debug").Bool(true),
+				key.New("payload").Bytes([]byte{1, 2}),
+			},
+			want: "checkout",
+		},
+		{
+			name: "/other",
+			attrs: []core.KeyValue{
+				key.New("payload").Bytes([]byte{1, 2}),
+				key.New("debug").Bool(true),
+			},
+			want: "debug",
+		},
+		{
+			name: "/other",
+			attrs: []core.KeyValue{
+				key.New("debug").Bool(true),
+				key.New("payload").Bytes([]byte{1, 3}),
+			},
+			want: "default",
+		},
+		{name: "/other", attrs: []core.KeyValue{key.New("debug").String("true")}, want: "default"},
+	} {
+		called = ""
+		s.ShouldSample(SamplingParameters{Name: tc.name, Attributes: tc.attrs})
+		if called != tc.want {
+			t.Errorf("%s %v: got rule %q, want %q", tc.name, tc.attrs, called, tc.want)
+		}
+	}
+}
+
+func TestRuleBasedSamplerStartAttributes(t *testing.T) {
+	var te testExporter
+	p := NewTracerProvider(
+		WithConfig(Config{DefaultSampler: RuleBasedSampler([]Rule{{
+			Attributes: []core.KeyValue{key.New("http.route").String("/checkout")},
+			Sampler:    AlwaysSample(),
+		}}, NeverSample())}),
+		WithSyncer(&te),
+	)
+	tracer := p.Tracer()
+	_, span := tracer.Start(context.Background(), "GET", apitrace.WithAttributes(key.New("http.route").String("/healthz")))
+	span.End()
+	_, span = tracer.Start(context.Background(), "POST", apitrace.WithAttributes(key.New("http.route").String("/checkout")))
+	span.End()
+
+	if len(te.spans) != 1 {
+		t.Fatalf("got %d exported spans, want 1", len(te.spans))
+	}
+	if got := te.spans[0].Name; got != "POST" {
+		t.Errorf("exported span: got %q, want %q", got, "POST")
+	}
+	if got := te.spans[0].Attributes; len(got) != 1 || got[0].Value.String != "/checkout" {
+		t.Errorf("start attributes of exported span: got %v", got)
+	}
+}
+
+func TestRuleBasedSamplerNilSampler(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		rules []Rule
+		def   Sampler
+	}{
+		{"nil rule sampler", []Rule{{Sampler: AlwaysSample()}, {Name: ExactName("a")}}, NeverSample()},
+		{"nil default sampler", []Rule{{Sampler: AlwaysSample()}}, nil},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: RuleBasedSampler did not panic", tc.name)
+				}
+			}()
+			RuleBasedSampler(tc.rules, tc.def)
+		}()
+	}
+}
@@ -15,31 +15,89 @@
 package trace
 
 import (
+	"fmt"
+
 	"go.opentelemetry.io/api/core"
+	apitrace "go.opentelemetry.io/api/trace"
 )
 
 const defaultSamplingProbability = 1e-4
 
-// Sampler decides whether a trace should be sampled and exported.
-type Sampler func(SamplingParameters) SamplingDecision
+// Sampler decides whether a trace should be sampled and exported. It is
+// consulted for every new span, including children of local spans; use
+// ParentBased to follow the decision of the parent span.
+//
+// The samplers of this package describe themselves with their name and
+// parameters, such as ProbabilitySampler{0.5}.
+type Sampler = apitrace.Sampler
+
+// SamplerFunc is a function implementing Sampler. Its description is
+// always "SamplerFunc"; use SamplerFuncWithDescription to tell samplers
+// apart in the descriptions of composite samplers.
+type SamplerFunc func(SamplingParameters) SamplingDecision
 
 // SamplingParameters contains the values passed to a Sampler.
-type SamplingParameters struct {
-	ParentContext   core.SpanContext
-	TraceID         core.TraceID
-	SpanID          uint64
-	Name            string
-	HasRemoteParent bool
+type SamplingParameters = apitrace.SamplingParameters
+
+// SamplingDecision is the value returned by a Sampler. Its attributes are
+// added to the span if it is recorded.
+type SamplingDecision = apitrace.Decision
+
+var _ Sampler = SamplerFunc(nil)
+
+// ShouldSample implements Sampler.
+func (f SamplerFunc) ShouldSample(p SamplingParameters) SamplingDecision {
+	return f(p)
+}
+
+// Description implements Sampler.
+func (f SamplerFunc) Description() string {
+	return "SamplerFunc"
+}
+
+// SamplerFuncWithDescription returns a Sampler implemented by f that
+// describes itself with description.
+func SamplerFuncWithDescription(description string, f func(SamplingParameters) SamplingDecision) Sampler {
+	return &describedSampler{description: description, sample: f}
+}
+
+// describedSampler is a Sampler implemented by a function together with
+// its description.
+type describedSampler struct {
+	description string
+	sample      func(SamplingParameters) SamplingDecision
+}
+
+func (s *describedSampler) ShouldSample(p SamplingParameters) SamplingDecision {
+	return s.sample(p)
+}
+
+func (s *describedSampler) Description() string {
+	return s.description
 }
 
-// SamplingDecision is the value returned by a Sampler.
-type SamplingDecision struct {
-	Sample bool
+// sampled returns a SamplingDecision to sample the span if sample is true
+// and to drop it otherwise.
+func sampled(sample bool, attrs ...core.KeyValue) SamplingDecision {
+	if sample {
+		return SamplingDecision{Result: apitrace.RecordAndSample, Attributes: attrs}
+	}
+	return SamplingDecision{Result: apitrace.Drop, Attributes: attrs}
 }
 
-// ProbabilitySampler returns a Sampler that samples a given fraction of traces.
+// ProbabilitySampler returns a Sampler that samples a given fraction of
+// traces, based on the trace ID only. Wrap it with ParentBased to sample
+// all spans of sampled traces.
+//
+// ProbabilitySampler used to sample every span with a sampled parent. It
+// no longer does, as samplers are now consulted for child spans too, so a
+// configuration of
+//
+//	Config{DefaultSampler: ProbabilitySampler(p)}
+//
+// breaks traces apart unless it is changed to
 //
-// It also samples spans whose parents are sampled.
+//	Config{DefaultSampler: ParentBased(ProbabilitySampler(p))}
 func ProbabilitySampler(fraction float64) Sampler {
 	if !(fraction >= 0) {
 		fraction = 0
@@ -48,13 +106,13 @@ func ProbabilitySampler(fraction float64) Sampler {
 	}
 
 	traceIDUpperBound := uint64(fraction * (1 << 63))
-	return Sampler(func(p SamplingParameters) SamplingDecision {
-		if p.ParentContext.IsSampled() {
-			return SamplingDecision{Sample: true}
-		}
-		x := p.TraceID.High >> 1
-		return SamplingDecision{Sample: x < traceIDUpperBound}
-	})
+	return &describedSampler{
+		description: fmt.Sprintf("ProbabilitySampler{%g}", fraction),
+		sample: func(p SamplingParameters) SamplingDecision {
+			x := p.TraceID.High >> 1
+			return sampled(x < traceIDUpperBound)
+		},
+	}
 }
 
 // AlwaysSample returns a Sampler that samples every trace.
@@ -62,14 +120,107 @@ func ProbabilitySampler(fraction float64) Sampler {
 // significant traffic: a new trace will be started and exported for every
 // request.
 func AlwaysSample() Sampler {
-	return func(p SamplingParameters) SamplingDecision {
-		return SamplingDecision{Sample: true}
+	return &describedSampler{
+		description: "AlwaysSample",
+		sample: func(p SamplingParameters) SamplingDecision {
+			return sampled(true)
+		},
 	}
 }
 
 // NeverSample returns a Sampler that samples no traces.
 func NeverSample() Sampler {
-	return func(p SamplingParameters) SamplingDecision {
-		return SamplingDecision{Sample: false}
+	return &describedSampler{
+		description: "NeverSample",
+		sample: func(p SamplingParameters) SamplingDecision {
+			return sampled(false)
+		},
+	}
+}
+
+// ParentBasedOption configures a sampler created by ParentBased.
+type ParentBasedOption func(*parentBased)
+
+type parentBased struct {
+	root                   Sampler
+	remoteParentSampled    Sampler
+	remoteParentNotSampled Sampler
+	localParentSampled     Sampler
+	localParentNotSampled  Sampler
+}
+
+// ParentBased returns a Sampler that delegates the decision for root spans
+// to root and the decision for spans with a parent to a sampler chosen by
+// whether the parent is remote and whether it is sampled. By default, the
+// decision of the parent is kept, so that either all or none of the spans
+// of a trace are sampled.
+func ParentBased(root Sampler, opts ...ParentBasedOption) Sampler {
+	pb := &parentBased{
+		root:                   root,
+		remoteParentSampled:    AlwaysSample(),
+		remoteParentNotSampled: NeverSample(),
+		localParentSampled:     AlwaysSample(),
+		localParentNotSampled:  NeverSample(),
+	}
+	for _, opt := range opts {
+		opt(pb)
+	}
+	return pb
+}
+
+func (pb *parentBased) ShouldSample(p SamplingParameters) SamplingDecision {
+	if !p.ParentContext.IsValid() {
+		return pb.root.ShouldSample(p)
+	}
+	if p.HasRemoteParent {
+		if p.ParentContext.IsSampled() {
+			return pb.remoteParentSampled.ShouldSample(p)
+		}
+		return pb.remoteParentNotSampled.ShouldSample(p)
+	}
+	if p.ParentContext.IsSampled() {
+		return pb.localParentSampled.ShouldSample(p)
+	}
+	return pb.localParentNotSampled.ShouldSample(p)
+}
+
+func (pb *parentBased) Description() string {
+	return fmt.Sprintf("ParentBased{root:%s,remoteParentSampled:%s,remoteParentNotSampled:%s,localParentSampled:%s,localParentNotSampled:%s}",
+		pb.root.Description(),
+		pb.remoteParentSampled.Description(),
+		pb.remoteParentNotSampled.Description(),
+		pb.localParentSampled.Description(),
+		pb.localParentNotSampled.Description())
+}
+
+// WithRemoteParentSampled sets the sampler for spans whose remote parent
+// is sampled. Default is AlwaysSample.
+func WithRemoteParentSampled(s Sampler) ParentBasedOption {
+	return func(pb *parentBased) {
+		pb.remoteParentSampled = s
+	}
+}
+
+// WithRemoteParentNotSampled sets the sampler for spans whose remote parent
+// is not sampled. Default is NeverSample.
+func WithRemoteParentNotSampled(s Sampler) ParentBasedOption {
+	return func(pb *parentBased) {
+		pb.remoteParentNotSampled = s
+	}
+}
+
+// WithLocalParentSampled sets the sampler for spans whose local parent is
+// sampled. Default is AlwaysSample.
+func WithLocalParentSampled(s Sampler) ParentBasedOption {
+	return func(pb *parentBased) {
+		pb.localParentSampled = s
+	}
+}
+
+// WithLocalParentNotSampled sets the sampler for spans whose local parent
+// is not sampled. Default is NeverSample.
+func WithLocalParentNotSampled(s Sampler) ParentBasedOption {
+	return func(pb *parentBased) {
+		pb.localParentNotSampled = s
 	}
 }
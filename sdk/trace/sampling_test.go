@@ -0,0 +1,322 @@
+// Copyright 2019, OpenTelemetry Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/api/core"
+	apitrace "go.opentelemetry.io/api/trace"
+)
+
+// named returns a sampler that records its name in *called.
+func named(name string, called *string, sample bool) Sampler {
+	return SamplerFunc(func(SamplingParameters) SamplingDecision {
+		*called = name
+		return sampled(sample)
+	})
+}
+
+func TestParentBased(t *testing.T) {
+	traceID := core.TraceID{High: 1, Low: 2}
+	sampled := core.SpanContext{TraceID: traceID, SpanID: 3, TraceFlags: core.TraceFlagsSampled}
+	notSampled := core.SpanContext{TraceID: traceID, SpanID: 3}
+
+	for _, tc := range []struct {
+		name   string
+		params SamplingParameters
+		want   string
+	}{
+		{"root", SamplingParameters{}, "root"},
+		{"remote sampled", SamplingParameters{ParentContext: sampled, HasRemoteParent: true}, "remoteSampled"},
+		{"remote not sampled", SamplingParameters{ParentContext: notSampled, HasRemoteParent: true}, "remoteNotSampled"},
+		{"local sampled", SamplingParameters{ParentContext: sampled}, "localSampled"},
+		{"local not sampled", SamplingParameters{ParentContext: notSampled}, "localNotSampled"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			var called string
+			s := ParentBased(named("root", &called, true),
+				WithRemoteParentSampled(named("remoteSampled", &called, true)),
+				WithRemoteParentNotSampled(named("remoteNotSampled", &called, true)),
+				WithLocalParentSampled(named("localSampled", &called, true)),
+				WithLocalParentNotSampled(named("localNotSampled", &called, true)),
+			)
+			s.ShouldSample(tc.params)
+			if called != tc.want {
+				t.Errorf("delegate: got %q, want %q", called, tc.want)
+			}
+		})
+	}
+}
+
+func TestParentBasedDefaults(t *testing.T) {
+	traceID := core.TraceID{High: 1, Low: 2}
+	s := ParentBased(NeverSample())
+	for _, tc := range []struct {
+		name   string
+		params SamplingParameters
+		want   bool
+	}{
+		{"root", SamplingParameters{}, false},
+		{"remote sampled", SamplingParameters{ParentContext: core.SpanContext{TraceID: traceID, SpanID: 3, TraceFlags: core.TraceFlagsSampled}, HasRemoteParent: true}, true},
+		{"remote not sampled", SamplingParameters{ParentContext: core.SpanContext{TraceID: traceID, SpanID: 3}, HasRemoteParent: true}, false},
+		{"local sampled", SamplingParameters{ParentContext: core.SpanContext{TraceID: traceID, SpanID: 3, TraceFlags: core.TraceFlagsSampled}}, true},
+		{"local not sampled", SamplingParameters{ParentContext: core.SpanContext{TraceID: traceID, SpanID: 3}}, false},
+	} {
+		if got := s.ShouldSample(tc.params).Sampled(); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestParentBasedIgnoreRemoteDecision(t *testing.T) {
+	// Never trust the sampling decision of a remote caller, but keep the
+	// decision within the process.
+	p := NewTracerProvider(WithConfig(Config{
+		DefaultSampler: ParentBased(ProbabilitySampler(0),
+			WithRemoteParentSampled(ProbabilitySampler(0)),
+		),
+	}))
+	remote := core.SpanContext{
+		TraceID:    core.TraceID{High: 1, Low: 2},
+		SpanID:     3,
+		TraceFlags: core.TraceFlagsSampled,
+	}
+	ctx, span := p.Tracer().Start(context.Background(), "server", apitrace.ChildOf(remote))
+	if span.SpanContext().IsSampled() {
+		t.Error("span with sampled remote parent sampled")
+	}
+	_, child := p.Tracer().Start(ctx, "child")
+	if child.SpanContext().IsSampled() {
+		t.Error("child of not sampled local parent sampled")
+	}
+
+	p.ApplyConfig(Config{DefaultSampler: ParentBased(NeverSample())})
+	ctx, span = p.Tracer().Start(context.Background(), "server", apitrace.ChildOf(remote))
+	if !span.SpanContext().IsSampled() {
+		t.Error("span with sampled remote parent not sampled by default")
+	}
+	_, child = p.Tracer().Start(ctx, "child")
+	if !child.SpanContext().IsSampled() {
+		t.Error("child of sampled local parent not sampled by default")
+	}
+}
+
+func TestProbabilitySamplerIgnoresParent(t *testing.T) {
+	s := ProbabilitySampler(0)
+	parent := core.SpanContext{TraceID: core.TraceID{High: 1}, SpanID: 3, TraceFlags: core.TraceFlagsSampled}
+	if s.ShouldSample(SamplingParameters{ParentContext: parent, TraceID: parent.TraceID}).Sampled() {
+		t.Error("ProbabilitySampler(0) sampled span with sampled parent")
+	}
+}
+
+// endedSpans is a SpanProcessor that records the names of ended spans.
+type endedSpans []string
+
+func (e *endedSpans) OnStart(*SpanData)  {}
+func (e *endedSpans) OnEnd(sd *SpanData) { *e = append(*e, sd.Name) }
+func (e *endedSpans) Shutdown()          {}
+
+func (e *endedSpans) ForceFlush(context.Context) error { return nil }
+
+func TestSamplingResult(t *testing.T) {
+	key := core.Key{Name: "sampler.reason"}
+	s := SamplerFunc(func(p SamplingParameters) SamplingDecision {
+		switch p.Name {
+		case "sampled":
+			return SamplingDecision{Result: apitrace.RecordAndSample, Attributes: []core.KeyValue{key.String("sampled")}}
+		case "recorded":
+			return SamplingDecision{Result: apitrace.RecordOnly, Attributes: []core.KeyValue{key.String("recorded")}}
+		}
+		return SamplingDecision{Result: apitrace.Drop}
+	})
+	var ended endedSpans
+	exported := exporter{}
+	p := NewTracerProvider(WithConfig(Config{DefaultSampler: s}), WithSpanProcessor(&ended), WithSyncer(exported))
+
+	for _, tc := range []struct {
+		name              string
+		recorded, sampled bool
+	}{
+		{"sampled", true, true},
+		{"recorded", true, false},
+		{"dropped", false, false},
+	} {
+		_, span := p.Tracer().Start(context.Background(), tc.name)
+		if got := span.IsRecordingEvents(); got != tc.recorded {
+			t.Errorf("%s: IsRecordingEvents() = %t, want %t", tc.name, got, tc.recorded)
+		}
+		if got := span.SpanContext().IsSampled(); got != tc.sampled {
+			t.Errorf("%s: IsSampled() = %t, want %t", tc.name, got, tc.sampled)
+		}
+		span.End()
+	}
+
+	if got, want := len(ended), 2; got != want {
+		t.Fatalf("got %d ended spans %v, want %d", got, ended, want)
+	}
+	if _, ok := exported["recorded"]; ok {
+		t.Error("record-only span exported")
+	}
+	sd, ok := exported["sampled"]
+	if !ok {
+		t.Fatal("sampled span not exported")
+	}
+	if got := sd.Attributes; len(got) != 1 || got[0].Key != key || got[0].Value.Emit() != "sampled" {
+		t.Errorf("sampler attributes: got %v, want %s=sampled", got, key.Name)
+	}
+}
+
+// reasonSampler is an apitrace.Sampler that is not part of the SDK.
+type reasonSampler struct{}
+
+func (reasonSampler) ShouldSample(p apitrace.SamplingParameters) apitrace.Decision {
+	return apitrace.Decision{
+		Result:     apitrace.RecordAndSample,
+		Attributes: []core.KeyValue{core.Key{Name: "sampler.span"}.String(p.Name)},
+	}
+}
+
+func (reasonSampler) Description() string { return "reasonSampler" }
+
+func TestAPISampler(t *testing.T) {
+	p := NewTracerProvider(WithConfig(Config{DefaultSampler: reasonSampler{}}))
+	_, span := p.Tracer().Start(context.Background(), "checkout")
+	if !span.SpanContext().IsSampled() {
+		t.Error("span not sampled by API sampler")
+	}
+
+	p.ApplyConfig(Config{DefaultSampler: apitrace.NeverSampleSampler()})
+	_, span = p.Tracer().Start(context.Background(), "checkout")
+	if span.SpanContext().IsSampled() || span.IsRecordingEvents() {
+		t.Error("span recorded by NeverSampleSampler")
+	}
+
+	// SDK samplers satisfy the API contract.
+	var s apitrace.Sampler = AlwaysSample()
+	if !s.ShouldSample(SamplingParameters{}).Sampled() {
+		t.Error("AlwaysSample did not sample through the API")
+	}
+}
+
+func TestSamplerDescription(t *testing.T) {
+	for _, tc := range []struct {
+		s    Sampler
+		want string
+	}{
+		{AlwaysSample(), "AlwaysSample"},
+		{NeverSample(), "NeverSample"},
+		{ProbabilitySampler(0.5), "ProbabilitySampler{0.5}"},
+		{ProbabilitySampler(1), "AlwaysSample"},
+		{RateLimitingSampler(10), "RateLimitingSampler{10}"},
+		{
+			ParentBased(ProbabilitySampler(0.5), WithRemoteParentSampled(NeverSample())),
+			"ParentBased{root:ProbabilitySampler{0.5},remoteParentSampled:NeverSample,remoteParentNotSampled:NeverSample,localParentSampled:AlwaysSample,localParentNotSampled:NeverSample}",
+		},
+		{
+			RuleBasedSampler([]Rule{{Sampler: AlwaysSample()}, {Sampler: ProbabilitySampler(0.5)}}, NeverSample()),
+			"RuleBasedSampler{rules:[AlwaysSample,ProbabilitySampler{0.5}],default:NeverSample}",
+		},
+		{SamplerFunc(func(SamplingParameters) SamplingDecision { return sampled(true) }), "SamplerFunc"},
+		{
+			SamplerFuncWithDescription("Sometimes", func(SamplingParameters) SamplingDecision { return sampled(true) }),
+			"Sometimes",
+		},
+	} {
+		if got := tc.s.Description(); got != tc.want {
+			t.Errorf("got description %q, want %q", got, tc.want)
+		}
+	}
+}
+
+// batchExporter is a BatchExporter that records the names of exported
+// spans.
+type batchExporter struct {
+	names []string
+}
+
+func (e *batchExporter) ExportSpans(sds []*SpanData) {
+	for _, sd := range sds {
+		e.names = append(e.names, sd.Name)
+	}
+}
+
+func TestProcessorsSkipRecordOnlySpans(t *testing.T) {
+	s := SamplerFunc(func(p SamplingParameters) SamplingDecision {
+		if p.Name == "recorded" {
+			return SamplingDecision{Result: apitrace.RecordOnly}
+		}
+		return SamplingDecision{Result: apitrace.RecordAndSample}
+	})
+	simple := exporter{}
+	batch := &batchExporter{}
+	bsp, err := NewBatchSpanProcessor(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := NewTracerProvider(
+		WithConfig(Config{DefaultSampler: s}),
+		WithSpanProcessor(NewSimpleSpanProcessor(simple)),
+		WithSpanProcessor(bsp),
+	)
+	for _, name := range []string{"recorded", "sampled"} {
+		_, span := p.Tracer().Start(context.Background(), name)
+		span.End()
+	}
+	p.Shutdown()
+
+	if _, ok := simple["recorded"]; ok || len(simple) != 1 {
+		t.Errorf("SimpleSpanProcessor exported %v, want only the sampled span", simple)
+	}
+	if len(batch.names) != 1 || batch.names[0] != "sampled" {
+		t.Errorf("BatchSpanProcessor exported %v, want only the sampled span", batch.names)
+	}
+}
+
+func TestWithSampler(t *testing.T) {
+	p := NewTracerProvider(WithConfig(Config{DefaultSampler: ParentBased(NeverSample())}))
+	tr := p.Tracer()
+
+	ctx, span := tr.Start(context.Background(), "debug", apitrace.WithSampler(apitrace.AlwaysSampleSampler()))
+	if !span.SpanContext().IsSampled() {
+		t.Error("span not sampled by WithSampler")
+	}
+	_, child := tr.Start(ctx, "child")
+	if !child.SpanContext().IsSampled() {
+		t.Error("child of forced span not sampled")
+	}
+	// A deferred decision is made by the overriding sampler as well.
+	_, span = tr.Start(context.Background(), "debug",
+		apitrace.WithSampler(apitrace.AlwaysSampleSampler()), apitrace.WithDeferredSampling())
+	if !span.SpanContext().IsSampled() {
+		t.Error("deferred span not sampled by WithSampler")
+	}
+
+	p.ApplyConfig(Config{DefaultSampler: ParentBased(AlwaysSample())})
+	ctx, span = tr.Start(context.Background(), "healthz", apitrace.WithSampler(NeverSample()))
+	if span.SpanContext().IsSampled() {
+		t.Error("span sampled despite WithSampler")
+	}
+	_, child = tr.Start(ctx, "child")
+	if child.SpanContext().IsSampled() {
+		t.Error("child of suppressed span sampled")
+	}
+
+	_, span = tr.Start(context.Background(), "default")
+	if !span.SpanContext().IsSampled() {
+		t.Error("span without WithSampler not sampled by default sampler")
+	}
+}
@@ -14,10 +14,15 @@
 
 package trace
 
+import "context"
+
 // SimpleSpanProcessor implements SpanProcessor interfaces. It is used by
 // exporters to receive SpanData synchronously when span is finished.
+// Only sampled spans are exported; spans that a sampler decided to record
+// without sampling them, see apitrace.RecordOnly, are skipped.
+// Export failures are passed to the ErrorHandler.
 type SimpleSpanProcessor struct {
-	exporter Exporter
+	syncer SpanSyncer
 }
 
 var _ SpanProcessor = (*SimpleSpanProcessor)(nil)
@@ -25,8 +30,17 @@ var _ SpanProcessor = (*SimpleSpanProcessor)(nil)
 // NewSimpleSpanProcessor creates a new instance of SimpleSpanProcessor
 // for a given exporter.
 func NewSimpleSpanProcessor(exporter Exporter) *SimpleSpanProcessor {
+	if exporter == nil {
+		return NewSimpleSpanProcessorFromSyncer(nil)
+	}
+	return NewSimpleSpanProcessorFromSyncer(SyncerFromExporter(exporter))
+}
+
+// NewSimpleSpanProcessorFromSyncer creates a new instance of
+// SimpleSpanProcessor for a given context-aware exporter.
+func NewSimpleSpanProcessorFromSyncer(syncer SpanSyncer) *SimpleSpanProcessor {
 	ssp := &SimpleSpanProcessor{
-		exporter: exporter,
+		syncer: syncer,
 	}
 	return ssp
 }
@@ -35,13 +49,22 @@ func NewSimpleSpanProcessor(exporter Exporter) *SimpleSpanProcessor {
 func (ssp *SimpleSpanProcessor) OnStart(sd *SpanData) {
 }
 
-// OnEnd method exports SpanData using associated exporter.
+// OnEnd method exports SpanData using associated exporter. Spans that are
+// recorded but not sampled are not exported.
 func (ssp *SimpleSpanProcessor) OnEnd(sd *SpanData) {
-	if ssp.exporter != nil {
-		ssp.exporter.ExportSpan(sd)
+	if ssp.syncer != nil && sd.SpanContext.IsSampled() {
+		if err := ssp.syncer.ExportSpan(context.Background(), sd); err != nil {
+			handleError(err)
+		}
 	}
 }
 
 // Shutdown method does nothing. There is no data to cleanup.
 func (ssp *SimpleSpanProcessor) Shutdown() {
 }
+
+// ForceFlush flushes the exporter if it implements Flusher. Spans are
+// passed to the exporter when they end, so there are no others to export.
+func (ssp *SimpleSpanProcessor) ForceFlush(ctx context.Context) error {
+	return flushExporter(ctx, ssp.syncer)
+}
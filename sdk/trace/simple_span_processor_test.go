@@ -16,6 +16,7 @@ package trace_test
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"go.opentelemetry.io/api/core"
@@ -75,3 +76,24 @@ func TestSimpleSpanProcessorOnEnd(t *testing.T) {
 		t.Errorf("SimplerSpanProcessor OnEnd() check: got %+v, want %+v\n", gotTraceID, wantTraceID)
 	}
 }
+
+type flushingExporter struct {
+	testExporter
+	err error
+}
+
+func (t *flushingExporter) ForceFlush(ctx context.Context) error {
+	return t.err
+}
+
+func TestSimpleSpanProcessorForceFlush(t *testing.T) {
+	if err := sdktrace.NewSimpleSpanProcessor(&testExporter{}).ForceFlush(context.Background()); err != nil {
+		t.Errorf("ForceFlush without Flusher: %v", err)
+	}
+
+	te := &flushingExporter{err: errors.New("flush failed")}
+	p := sdktrace.NewTracerProvider(sdktrace.WithSyncer(te))
+	if err := p.ForceFlush(context.Background()); err != te.err {
+		t.Errorf("ForceFlush: got %v, want %v", err, te.err)
+	}
+}
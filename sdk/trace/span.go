@@ -17,6 +17,7 @@ package trace
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"google.golang.org/grpc/codes"
@@ -52,16 +53,40 @@ type span struct {
 	//*spanStore
 	endOnce sync.Once
 
-	executionTracerTaskEnd func()          // ends the execution tracer span
-	tracer                 apitrace.Tracer // tracer used to create span.
+	executionTracerTaskEnd func()  // ends the execution tracer span
+	tracer                 *tracer // tracer used to create span.
+
+	// sampler is the sampler passed with WithSampler, if any, otherwise
+	// it is nil and the default sampler of the provider is consulted.
+	sampler Sampler
+
+	// pending holds the input of a deferred sampling decision until it is
+	// made. It is protected by mu.
+	pending *samplingData
+
+	// deferred is 1 until a deferred sampling decision is made, and
+	// dropped is 1 if that decision stopped the recording of the span.
+	// They are accessed atomically, so that SpanContext and
+	// IsRecordingEvents do not lock.
+	deferred uint32
+	dropped  uint32
+
+	// cfg is the configuration of the provider when the span was started.
+	// Its limits apply to the span.
+	cfg *Config
 }
 
 var _ apitrace.Span = &span{}
 
+// SpanContext returns the span context of the span. For spans started with
+// WithDeferredSampling, the first call makes the sampling decision.
 func (s *span) SpanContext() core.SpanContext {
 	if s == nil {
 		return core.EmptySpanContext()
 	}
+	if atomic.LoadUint32(&s.deferred) == 1 {
+		s.decideSampling()
+	}
 	return s.spanContext
 }
 
@@ -69,7 +94,7 @@ func (s *span) IsRecordingEvents() bool {
 	if s == nil {
 		return false
 	}
-	return s.data != nil
+	return s.data != nil && atomic.LoadUint32(&s.dropped) == 0
 }
 
 func (s *span) SetStatus(status codes.Code) {
@@ -98,12 +123,22 @@ func (s *span) SetAttributes(attributes ...core.KeyValue) {
 	s.copyToCappedAttributes(attributes...)
 }
 
-// ModifyAttribute does nothing.
+// ModifyAttribute applies the mutator to the attributes of the span like
+// tag.Map.Apply does. A mutator with an undefined key is ignored.
 func (s *span) ModifyAttribute(mutator apitag.Mutator) {
+	if !s.IsRecordingEvents() || !mutator.KeyValue.Key.Defined() {
+		return
+	}
+	s.applyToCappedAttributes(mutator)
 }
 
-// ModifyAttributes does nothing.
+// ModifyAttributes applies the mutators in order to the attributes of the
+// span like tag.Map.Apply does.
 func (s *span) ModifyAttributes(mutators ...apitag.Mutator) {
+	if !s.IsRecordingEvents() {
+		return
+	}
+	s.applyToCappedAttributes(mutators...)
 }
 
 func (s *span) End(options ...apitrace.EndOption) {
@@ -122,9 +157,14 @@ func (s *span) End(options ...apitrace.EndOption) {
 		opt(&opts)
 	}
 	s.endOnce.Do(func() {
-		exp, _ := exporters.Load().(exportersMap)
-		sps, _ := spanProcessors.Load().(spanProcessorMap)
-		mustExportOrProcess := len(sps) > 0 || (s.spanContext.IsSampled() && len(exp) > 0)
+		sampled := s.SpanContext().IsSampled()
+		if !s.IsRecordingEvents() {
+			// A deferred sampling decision dropped the span.
+			return
+		}
+		exp, _ := s.tracer.provider.exporters.Load().(exportersMap)
+		sps, _ := s.tracer.provider.spanProcessors.Load().(spanProcessorMap)
+		mustExportOrProcess := len(sps) > 0 || (sampled && len(exp) > 0)
 		// TODO(rghetia): when exporter is migrated to use processors simply check for the number
 		// of processors. Exporter will export based on sampling.
 		if mustExportOrProcess {
@@ -135,7 +175,7 @@ func (s *span) End(options ...apitrace.EndOption) {
 				sd.EndTime = opts.EndTime
 			}
 			// Sampling check would be in the processor if the processor is used for exporting.
-			if s.spanContext.IsSampled() {
+			if sampled {
 				for e := range exp {
 					e.ExportSpan(sd)
 				}
@@ -166,8 +206,11 @@ func (s *span) AddEventWithTimestamp(ctx context.Context, timestamp time.Time, m
 }
 
 func (s *span) addEventWithTimestamp(timestamp time.Time, msg string, attrs ...core.KeyValue) {
+	attrs, dropped, truncated := limitAttributes(s.cfg, attrs, s.cfg.MaxAttributesPerEvent)
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.data.DroppedEventAttributeCount += dropped
+	s.data.TruncatedAttributeValueCount += truncated
 	s.messageEvents.add(Event{
 		Message:    msg,
 		Attributes: attrs,
@@ -175,31 +218,15 @@ func (s *span) addEventWithTimestamp(timestamp time.Time, msg string, attrs ...c
 	})
 }
 
+// SetName sets the name of the span. The sampling decision is not changed;
+// start the span with WithDeferredSampling to sample it by its final name.
 func (s *span) SetName(name string) {
-	if s.data == nil {
-		// TODO: now what?
+	if !s.IsRecordingEvents() {
 		return
 	}
+	s.mu.Lock()
 	s.data.Name = name
-	// SAMPLING
-	noParent := s.data.ParentSpanID == 0
-	var ctx core.SpanContext
-	if noParent {
-		ctx = core.EmptySpanContext()
-	} else {
-		// FIXME: Where do we get the parent context from?
-		// From SpanStore?
-		ctx = s.data.SpanContext
-	}
-	data := samplingData{
-		noParent:     noParent,
-		remoteParent: s.data.HasRemoteParent,
-		parent:       ctx,
-		name:         name,
-		cfg:          config.Load().(*Config),
-		span:         s,
-	}
-	makeSamplingDecision(data)
+	s.mu.Unlock()
 }
 
 // AddLink implements Span interface. Specified link is added to the span.
@@ -229,8 +256,12 @@ func (s *span) Link(sc core.SpanContext, attrs ...core.KeyValue) {
 }
 
 func (s *span) addLink(link apitrace.Link) {
+	var dropped, truncated int
+	link.Attributes, dropped, truncated = limitAttributes(s.cfg, link.Attributes, s.cfg.MaxAttributesPerLink)
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.data.DroppedLinkAttributeCount += dropped
+	s.data.TruncatedAttributeValueCount += truncated
 	s.links.add(link)
 }
 
@@ -289,7 +320,29 @@ func (s *span) copyToCappedAttributes(attributes ...core.KeyValue) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	for _, a := range attributes {
-		s.lruAttributes.add(a.Key, a.Value)
+		s.addAttributeLocked(a)
+	}
+}
+
+// addAttributeLocked adds the attribute with its value truncated to the
+// configured length. It requires that s.mu is held.
+func (s *span) addAttributeLocked(kv core.KeyValue) {
+	kv, truncated := truncateAttribute(s.cfg, kv)
+	if truncated {
+		s.data.TruncatedAttributeValueCount++
+	}
+	s.lruAttributes.add(kv.Key, kv.Value)
+}
+
+func (s *span) applyToCappedAttributes(mutators ...apitag.Mutator) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, m := range mutators {
+		var truncated bool
+		m.KeyValue, truncated = truncateAttribute(s.cfg, m.KeyValue)
+		if s.lruAttributes.apply(m) && truncated {
+			s.data.TruncatedAttributeValueCount++
+		}
 	}
 }
 
@@ -302,12 +355,15 @@ func (s *span) addChild() {
 	s.mu.Unlock()
 }
 
-func startSpanInternal(name string, parent core.SpanContext, remoteParent bool, o apitrace.SpanOptions) *span {
+func startSpanInternal(tr *tracer, name string, parent core.SpanContext, remoteParent bool, o apitrace.SpanOptions) *span {
 	var noParent bool
 	span := &span{}
 	span.spanContext = parent
+	span.tracer = tr
+	span.sampler = o.Sampler
 
-	cfg := config.Load().(*Config)
+	cfg := tr.provider.getConfig()
+	span.cfg = cfg
 
 	if parent == core.EmptySpanContext() {
 		span.spanContext.TraceID = cfg.IDGenerator.NewTraceID()
@@ -319,14 +375,26 @@ func startSpanInternal(name string, parent core.SpanContext, remoteParent bool,
 		remoteParent: remoteParent,
 		parent:       parent,
 		name:         name,
+		attributes:   o.Attributes,
+		recordEvent:  o.RecordEvent,
 		cfg:          cfg,
 		span:         span,
 	}
-	makeSamplingDecision(data)
+	var decision SamplingDecision
+	if o.DeferSampling {
+		// The span is recorded until decideSampling consults the
+		// sampler.
+		span.pending = &data
+		span.deferred = 1
+		span.spanContext.TraceFlags &^= core.TraceFlagsSampled
+		decision.Result = apitrace.RecordOnly
+	} else {
+		decision = makeSamplingDecision(data)
+	}
 
 	// TODO: [rghetia] restore when spanstore is added.
 	// if !internal.LocalSpanStoreEnabled && !span.spanContext.IsSampled() && !o.RecordEvent {
-	if !span.spanContext.IsSampled() && !o.RecordEvent {
+	if !decision.Recorded() && !o.RecordEvent {
 		return span
 	}
 
@@ -341,10 +409,19 @@ func startSpanInternal(name string, parent core.SpanContext, remoteParent bool,
 		//SpanKind:        o.SpanKind,
 		Name:            name,
 		HasRemoteParent: remoteParent,
+		TracerName:      tr.name,
+		TracerVersion:   tr.version,
+		Resource:        tr.resource(cfg),
 	}
 	span.lruAttributes = newLruMap(cfg.MaxAttributesPerSpan)
 	span.messageEvents = newEvictedQueue(cfg.MaxEventsPerSpan)
 	span.links = newEvictedQueue(cfg.MaxLinksPerSpan)
+	for _, kv := range o.Attributes {
+		span.addAttributeLocked(kv)
+	}
+	for _, kv := range decision.Attributes {
+		span.addAttributeLocked(kv)
+	}
 
 	if !noParent {
 		span.data.ParentSpanID = parent.SpanID
@@ -366,33 +443,66 @@ type samplingData struct {
 	remoteParent bool
 	parent       core.SpanContext
 	name         string
+	attributes   []core.KeyValue
+	recordEvent  bool
 	cfg          *Config
 	span         *span
 }
 
-func makeSamplingDecision(data samplingData) {
-	if data.noParent || data.remoteParent {
-		// If this span is the child of a local span and no
-		// Sampler is set in the options, keep the parent's
-		// TraceFlags.
-		//
-		// Otherwise, consult the Sampler in the options if it
-		// is non-nil, otherwise the default sampler.
-		sampler := data.cfg.DefaultSampler
-		//if o.Sampler != nil {
-		//	sampler = o.Sampler
-		//}
-		spanContext := &data.span.spanContext
-		sampled := sampler(SamplingParameters{
-			ParentContext:   data.parent,
-			TraceID:         spanContext.TraceID,
-			SpanID:          spanContext.SpanID,
-			Name:            data.name,
-			HasRemoteParent: data.remoteParent}).Sample
-		if sampled {
-			spanContext.TraceFlags |= core.TraceFlagsSampled
-		} else {
-			spanContext.TraceFlags &^= core.TraceFlagsSampled
+// decideSampling makes a deferred sampling decision with the current name
+// and attributes of the span. If the span is recorded, the span processors
+// are notified of its start, otherwise the span stops recording.
+func (s *span) decideSampling() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pending == nil {
+		return
+	}
+	data := *s.pending
+	s.pending = nil
+	data.name = s.data.Name
+	data.attributes = s.lruAttributesToAttributeMap()
+	decision := makeSamplingDecision(data)
+	s.data.SpanContext = s.spanContext
+	if decision.Recorded() || data.recordEvent {
+		for _, kv := range decision.Attributes {
+			s.addAttributeLocked(kv)
+		}
+		// The processors are notified before the decision is published,
+		// so that End cannot pass the span to them first.
+		sps, _ := s.tracer.provider.spanProcessors.Load().(spanProcessorMap)
+		for sp := range sps {
+			sp.OnStart(s.data)
 		}
+	} else {
+		atomic.StoreUint32(&s.dropped, 1)
+	}
+	atomic.StoreUint32(&s.deferred, 0)
+}
+
+// makeSamplingDecision sets the sampled flag of the span context of the
+// span and returns the decision of the sampler.
+func makeSamplingDecision(data samplingData) SamplingDecision {
+	// Consult the Sampler in the options if it is non-nil,
+	// otherwise the default sampler.
+	sampler := data.cfg.DefaultSampler
+	if data.span.sampler != nil {
+		sampler = data.span.sampler
+	}
+	spanContext := &data.span.spanContext
+	decision := sampler.ShouldSample(SamplingParameters{
+		ParentContext:   data.parent,
+		TraceID:         spanContext.TraceID,
+		SpanID:          spanContext.SpanID,
+		Name:            data.name,
+		HasRemoteParent: data.remoteParent,
+		Attributes:      data.attributes,
+		TracerName:      data.span.tracer.name,
+		TracerVersion:   data.span.tracer.version})
+	if decision.Sampled() {
+		spanContext.TraceFlags |= core.TraceFlagsSampled
+	} else {
+		spanContext.TraceFlags &^= core.TraceFlagsSampled
 	}
+	return decision
 }
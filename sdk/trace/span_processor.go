@@ -14,10 +14,7 @@
 
 package trace
 
-import (
-	"sync"
-	"sync/atomic"
-)
+import "context"
 
 // SpanProcessor is interface to add hooks to start and end method invocations.
 type SpanProcessor interface {
@@ -34,42 +31,30 @@ type SpanProcessor interface {
 	// data. No calls to OnStart and OnEnd method is invoked after Shutdown call is
 	// made. It should not be blocked indefinitely.
 	Shutdown()
+
+	// ForceFlush exports all spans that ended before the call and flushes the
+	// exporter of the processor. It returns the error of the context if the
+	// context is done first.
+	ForceFlush(ctx context.Context) error
 }
 
 type spanProcessorMap map[SpanProcessor]struct{}
 
-var (
-	mu             sync.Mutex
-	spanProcessors atomic.Value
-)
-
-// RegisterSpanProcessor adds to the list of SpanProcessors that will receive sampled
-// trace spans.
+// RegisterSpanProcessor adds to the list of SpanProcessors of the default
+// TracerProvider that will receive sampled trace spans.
 func RegisterSpanProcessor(e SpanProcessor) {
-	mu.Lock()
-	defer mu.Unlock()
-	new := make(spanProcessorMap)
-	if old, ok := spanProcessors.Load().(spanProcessorMap); ok {
-		for k, v := range old {
-			new[k] = v
-		}
-	}
-	new[e] = struct{}{}
-	spanProcessors.Store(new)
+	defaultProvider.RegisterSpanProcessor(e)
 }
 
-// UnregisterSpanProcessor removes from the list of SpanProcessors the SpanProcessor that was
-// registered with the given name.
+// UnregisterSpanProcessor removes from the list of SpanProcessors of the
+// default TracerProvider the SpanProcessor that was registered with the
+// given name.
 func UnregisterSpanProcessor(s SpanProcessor) {
-	mu.Lock()
-	defer mu.Unlock()
-	new := make(spanProcessorMap)
-	if old, ok := spanProcessors.Load().(spanProcessorMap); ok {
-		for k, v := range old {
-			new[k] = v
-		}
-	}
-	delete(new, s)
-	spanProcessors.Store(new)
-	s.Shutdown()
+	defaultProvider.UnregisterSpanProcessor(s)
+}
+
+// ForceFlush flushes the span processors and exporters of the default
+// TracerProvider.
+func ForceFlush(ctx context.Context) error {
+	return defaultProvider.ForceFlush(ctx)
 }
@@ -45,6 +45,10 @@ func (t *testSpanProcesor) Shutdown() {
 	t.shutdownCount++
 }
 
+func (t *testSpanProcesor) ForceFlush(ctx context.Context) error {
+	return nil
+}
+
 func TestRegisterSpanProcessort(t *testing.T) {
 	name := "Register span processor before span starts"
 	sp := NewTestSpanProcessor()
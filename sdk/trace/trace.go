@@ -15,49 +15,13 @@
 package trace
 
 import (
-	crand "crypto/rand"
-	"encoding/binary"
-	"math/rand"
-	"sync"
-	"sync/atomic"
-
 	apitrace "go.opentelemetry.io/api/trace"
 )
 
-var config atomic.Value // access atomically
-
-func init() {
-	gen := &defaultIDGenerator{}
-	// initialize traceID and spanID generators.
-	var rngSeed int64
-	for _, p := range []interface{}{
-		&rngSeed, &gen.traceIDAdd, &gen.nextSpanID, &gen.spanIDInc,
-	} {
-		_ = binary.Read(crand.Reader, binary.LittleEndian, p)
-	}
-	gen.traceIDRand = rand.New(rand.NewSource(rngSeed))
-	gen.spanIDInc |= 1
-
-	config.Store(&Config{
-		DefaultSampler:       ProbabilitySampler(defaultSamplingProbability),
-		IDGenerator:          gen,
-		MaxAttributesPerSpan: DefaultMaxAttributesPerSpan,
-		MaxEventsPerSpan:     DefaultMaxEventsPerSpan,
-		MaxLinksPerSpan:      DefaultMaxLinksPerSpan,
-	})
-}
-
-var tr *tracer
-var registerOnce sync.Once
-
-// Register registers tracer implementation as default Tracer.
-// It creates single instance of tracer and registers it once.
+// Register registers the tracer of the default TracerProvider as default
+// Tracer. It registers it once.
 // Recommended use is to call Register in main() of an
 // application before calling any tracing api.
 func Register() apitrace.Tracer {
-	registerOnce.Do(func() {
-		tr = &tracer{}
-		apitrace.SetGlobalTracer(tr)
-	})
-	return tr
+	return defaultProvider.Register()
 }
@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -27,6 +28,7 @@ import (
 
 	"go.opentelemetry.io/api/core"
 	"go.opentelemetry.io/api/key"
+	apitag "go.opentelemetry.io/api/tag"
 	apitrace "go.opentelemetry.io/api/trace"
 )
 
@@ -42,7 +44,7 @@ func init() {
 
 func setupDefaultSamplerConfig() {
 	// no random sampling, but sample children of sampled spans.
-	ApplyConfig(Config{DefaultSampler: ProbabilitySampler(0)})
+	ApplyConfig(Config{DefaultSampler: ParentBased(ProbabilitySampler(0))})
 }
 
 type testExporter struct {
@@ -62,64 +64,190 @@ func TestStartSpan(t *testing.T) {
 }
 
 func TestSetName(t *testing.T) {
-	samplerIsCalled := false
-	fooSampler := Sampler(func(p SamplingParameters) SamplingDecision {
-		samplerIsCalled = true
-		t.Logf("called sampler for name %q", p.Name)
-		return SamplingDecision{Sample: strings.HasPrefix(p.Name, "foo")}
+	var calls []string
+	fooSampler := SamplerFunc(func(p SamplingParameters) SamplingDecision {
+		calls = append(calls, p.Name)
+		return sampled(strings.HasPrefix(p.Name, "foo"))
 	})
 	ApplyConfig(Config{DefaultSampler: fooSampler})
 	defer setupDefaultSamplerConfig()
-	type testCase struct {
-		name          string
-		newName       string
-		sampledBefore bool
-		sampledAfter  bool
-	}
-	for idx, tt := range []testCase{
-		{ // 0
-			name:          "foobar",
-			newName:       "foobaz",
-			sampledBefore: true,
-			sampledAfter:  true,
-		},
-		{ // 1
-			name:          "foobar",
-			newName:       "barbaz",
-			sampledBefore: true,
-			sampledAfter:  false,
-		},
-		{ // 2
-			name:          "barbar",
-			newName:       "barbaz",
-			sampledBefore: false,
-			sampledAfter:  false,
-		},
-		{ // 3
-			name:          "barbar",
-			newName:       "foobar",
-			sampledBefore: false,
-			sampledAfter:  true,
-		},
+	for idx, tt := range []struct {
+		name    string
+		newName string
+		sampled bool
+	}{
+		{name: "foobar", newName: "foobaz", sampled: true},
+		{name: "foobar", newName: "barbaz", sampled: true},
+		{name: "barbar", newName: "barbaz", sampled: false},
+		{name: "barbar", newName: "foobar", sampled: false},
 	} {
-		span := startNamedSpan(tt.name)
-		if !samplerIsCalled {
-			t.Errorf("%d: the sampler was not even called during span creation", idx)
+		calls = nil
+		var te testExporter
+		RegisterExporter(&te)
+		_, span := apitrace.GlobalTracer().Start(context.Background(), tt.name, apitrace.WithRecordEvents())
+		span.SetName(tt.newName)
+		span.End()
+		UnregisterExporter(&te)
+
+		if diff := cmp.Diff([]string{tt.name}, calls); diff != "" {
+			t.Errorf("%d: sampler calls: -want +got %s", idx, diff)
 		}
-		samplerIsCalled = false
-		if gotSampledBefore := span.SpanContext().IsSampled(); tt.sampledBefore != gotSampledBefore {
-			t.Errorf("%d: invalid sampling decision before rename, expected %v, got %v", idx, tt.sampledBefore, gotSampledBefore)
+		if got := span.SpanContext().IsSampled(); got != tt.sampled {
+			t.Errorf("%d: sampled after rename: got %t, want %t", idx, got, tt.sampled)
 		}
-		span.SetName(tt.newName)
-		if !samplerIsCalled {
-			t.Errorf("%d: the sampler was not even called during span rename", idx)
+		if tt.sampled {
+			if len(te.spans) != 1 || te.spans[0].Name != tt.newName {
+				t.Errorf("%d: exported spans %v, want one span named %q", idx, te.spans, tt.newName)
+			}
+		} else if len(te.spans) != 0 {
+			t.Errorf("%d: exported unsampled span", idx)
 		}
-		samplerIsCalled = false
-		if gotSampledAfter := span.SpanContext().IsSampled(); tt.sampledAfter != gotSampledAfter {
-			t.Errorf("%d: invalid sampling decision after rename, expected %v, got %v", idx, tt.sampledAfter, gotSampledAfter)
+	}
+}
+
+func TestDeferredSampling(t *testing.T) {
+	var calls []SamplingParameters
+	fooSampler := SamplerFunc(func(p SamplingParameters) SamplingDecision {
+		calls = append(calls, p)
+		return sampled(strings.HasPrefix(p.Name, "foo"))
+	})
+	ApplyConfig(Config{DefaultSampler: ParentBased(fooSampler)})
+	defer setupDefaultSamplerConfig()
+	var te testExporter
+	RegisterExporter(&te)
+	defer UnregisterExporter(&te)
+
+	tr := apitrace.GlobalTracer()
+	ctx, span := tr.Start(context.Background(), "barbar", apitrace.WithDeferredSampling())
+	if !span.IsRecordingEvents() {
+		t.Fatal("span with deferred sampling not recorded")
+	}
+	if len(calls) != 0 {
+		t.Fatalf("sampler called at start of deferred span: %v", calls)
+	}
+	span.SetName("foobar")
+	span.SetAttribute(core.Key{Name: "route"}.String("/checkout"))
+
+	// Starting a child reads the span context and makes the decision.
+	_, child := tr.Start(ctx, "child")
+	if !child.SpanContext().IsSampled() {
+		t.Error("child of sampled deferred span not sampled")
+	}
+	if len(calls) != 1 {
+		t.Fatalf("got %d sampler calls, want 1", len(calls))
+	}
+	if got := calls[0]; got.Name != "foobar" || len(got.Attributes) != 1 || got.Attributes[0].Value.Emit() != "/checkout" {
+		t.Errorf("sampler parameters: got name %q and attributes %v", got.Name, got.Attributes)
+	}
+
+	span.SetName("barbaz")
+	child.End()
+	span.End()
+	if len(calls) != 1 {
+		t.Errorf("sampler consulted again: %d calls", len(calls))
+	}
+	if len(te.spans) != 2 || !te.spans[1].SpanContext.IsSampled() || te.spans[1].Name != "barbaz" {
+		t.Errorf("exported spans: %v", te.spans)
+	}
+
+	// Without reading its context, the decision is made when the span ends.
+	te.spans = nil
+	_, span = tr.Start(context.Background(), "foo", apitrace.WithDeferredSampling())
+	span.SetName("bar")
+	span.End()
+	if span.SpanContext().IsSampled() || len(te.spans) != 0 {
+		t.Error("deferred span renamed to bar sampled")
+	}
+}
+
+// spanEvents is a SpanProcessor that records the start and end of spans.
+type spanEvents []string
+
+func (e *spanEvents) OnStart(sd *SpanData) { *e = append(*e, "start "+sd.Name) }
+func (e *spanEvents) OnEnd(sd *SpanData)   { *e = append(*e, "end "+sd.Name) }
+func (e *spanEvents) Shutdown()            {}
+
+func (e *spanEvents) ForceFlush(context.Context) error { return nil }
+
+func TestDeferredSamplingDrop(t *testing.T) {
+	dropSampler := SamplerFunc(func(p SamplingParameters) SamplingDecision {
+		if strings.HasPrefix(p.Name, "drop") {
+			return SamplingDecision{Result: apitrace.Drop}
+		}
+		return SamplingDecision{Result: apitrace.RecordAndSample}
+	})
+	var events spanEvents
+	p := NewTracerProvider(WithConfig(Config{DefaultSampler: dropSampler}), WithSpanProcessor(&events))
+	tr := p.Tracer()
+
+	_, span := tr.Start(context.Background(), "span", apitrace.WithDeferredSampling())
+	span.SetName("dropped")
+	if len(events) != 0 {
+		t.Fatalf("processors notified before the sampling decision: %v", events)
+	}
+	if span.SpanContext().IsSampled() {
+		t.Error("dropped span sampled")
+	}
+	if span.IsRecordingEvents() {
+		t.Error("dropped span still recording")
+	}
+	span.SetName("kept")
+	span.End()
+
+	_, span = tr.Start(context.Background(), "span", apitrace.WithDeferredSampling())
+	span.SetName("kept")
+	span.End()
+
+	if diff := cmp.Diff([]string(events), []string{"start kept", "end kept"}); diff != "" {
+		t.Errorf("span processor calls: -got +want %s", diff)
+	}
+}
+
+// lockedExporter is a testExporter that can be used concurrently.
+type lockedExporter struct {
+	mu sync.Mutex
+	testExporter
+}
+
+func (e *lockedExporter) ExportSpan(s *SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.testExporter.ExportSpan(s)
+}
+
+func TestSpanConcurrentAccess(t *testing.T) {
+	// Run with the race detector.
+	ApplyConfig(Config{DefaultSampler: ParentBased(AlwaysSample())})
+	defer setupDefaultSamplerConfig()
+	var te lockedExporter
+	RegisterExporter(&te)
+	defer UnregisterExporter(&te)
+
+	for _, opts := range [][]apitrace.SpanOption{nil, {apitrace.WithDeferredSampling()}} {
+		ctx, span := apitrace.GlobalTracer().Start(context.Background(), "span", opts...)
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < 50; j++ {
+					span.SetName(fmt.Sprintf("span-%d-%d", i, j))
+					span.SetAttribute(core.Key{Name: "i"}.Int(i))
+					span.AddEvent(ctx, "event")
+					_ = span.SpanContext()
+					_, child := apitrace.GlobalTracer().Start(ctx, "child")
+					child.End()
+				}
+			}(i)
 		}
+		wg.Wait()
 		span.End()
 	}
+	for _, sd := range te.spans {
+		if !sd.SpanContext.IsSampled() {
+			t.Errorf("span %q not sampled", sd.Name)
+		}
+	}
 }
 
 func TestRecordingIsOff(t *testing.T) {
@@ -238,6 +366,144 @@ func TestSetSpanAttributesOverLimit(t *testing.T) {
 	}
 }
 
+func TestModifySpanAttributes(t *testing.T) {
+	spans := make(exporter)
+	p := NewTracerProvider(
+		WithConfig(Config{DefaultSampler: AlwaysSample(), MaxAttributesPerSpan: 3}),
+		WithSyncer(spans),
+	)
+	k1, k2, k3, k4 := key.New("key1"), key.New("key2"), key.New("key3"), key.New("key4")
+
+	_, span := p.Tracer().Start(context.Background(), "span")
+	span.SetAttributes(k1.String("value1"), k2.String("value2"))
+	span.ModifyAttributes(
+		apitag.Insert(k1.String("ignored")),  // key1 exists
+		apitag.Update(k2.String("updated")),  // replace key2
+		apitag.Update(k3.String("ignored")),  // key3 does not exist
+		apitag.Insert(k3.String("inserted")), // add key3
+		apitag.Delete(k1),                    // remove key1
+		apitag.Upsert(k4.String("upserted")), // add key4 without eviction
+	)
+	span.ModifyAttribute(apitag.Delete(key.New("missing")))
+	span.ModifyAttribute(apitag.Upsert(core.KeyValue{Value: core.Value{Type: core.STRING, String: "no key"}}))
+	span.End()
+
+	want := []core.KeyValue{k2.String("updated"), k3.String("inserted"), k4.String("upserted")}
+	if diff := cmp.Diff(want, spans["span"].Attributes); diff != "" {
+		t.Errorf("ModifyAttributes: -want +got %s", diff)
+	}
+	if got := spans["span"].DroppedAttributeCount; got != 0 {
+		t.Errorf("DroppedAttributeCount: got %d, want 0", got)
+	}
+
+	_, span = p.Tracer().Start(context.Background(), "over limit")
+	span.SetAttributes(k1.String("value1"), k2.String("value2"), k3.String("value3"))
+	span.ModifyAttributes(
+		apitag.Delete(k2),                    // make room for key4
+		apitag.Insert(k4.String("value4")),   // no eviction
+		apitag.Upsert(k2.String("value2")),   // evict key1
+		apitag.Update(k1.String("ignored")),  // key1 was evicted
+		apitag.Insert(k1.String("restored")), // evict key3
+	)
+	span.End()
+
+	want = []core.KeyValue{k4.String("value4"), k2.String("value2"), k1.String("restored")}
+	if diff := cmp.Diff(want, spans["over limit"].Attributes); diff != "" {
+		t.Errorf("ModifyAttributes over limit: -want +got %s", diff)
+	}
+	if got := spans["over limit"].DroppedAttributeCount; got != 2 {
+		t.Errorf("DroppedAttributeCount: got %d, want 2", got)
+	}
+}
+
+func TestAttributeValueLengthLimits(t *testing.T) {
+	spans := make(exporter)
+	sql, url, raw := key.New("db.statement"), key.New("http.url"), key.New("raw")
+	p := NewTracerProvider(
+		WithConfig(Config{
+			DefaultSampler:                AlwaysSample(),
+			MaxAttributeValueLength:       4,
+			MaxAttributeValueLengthPerKey: map[core.Key]int{sql: 8, url: 0},
+		}),
+		WithSyncer(spans),
+	)
+
+	_, span := p.Tracer().Start(context.Background(), "span",
+		apitrace.WithAttributes(raw.Bytes([]byte("0123456789"))))
+	span.SetAttributes(
+		sql.String("SELECT * FROM orders"),
+		url.String("https://example.com/checkout"),
+		key.New("name").String("abcé"), // é takes the bytes 3 and 4
+		key.New("short").String("abc"),
+		key.New("count").Int(123456),
+	)
+	span.ModifyAttribute(apitag.Insert(key.New("inserted").String("abcdef")))
+	span.ModifyAttribute(apitag.Insert(sql.String("not stored, not counted")))
+	span.AddEvent(context.Background(), "event", key.New("message").String("abcdef"))
+	span.Link(core.SpanContext{TraceID: tid, SpanID: sid}, key.New("link").String("abcdef"))
+	span.End()
+
+	got := spans["span"]
+	want := []core.KeyValue{
+		raw.Bytes([]byte("0123")),
+		sql.String("SELECT *"),
+		url.String("https://example.com/checkout"),
+		key.New("name").String("abc"),
+		key.New("short").String("abc"),
+		key.New("count").Int(123456),
+		key.New("inserted").String("abcd"),
+	}
+	if diff := cmp.Diff(want, got.Attributes); diff != "" {
+		t.Errorf("Attributes: -want +got %s", diff)
+	}
+	if diff := cmp.Diff([]core.KeyValue{key.New("message").String("abcd")}, got.MessageEvents[0].Attributes); diff != "" {
+		t.Errorf("event attributes: -want +got %s", diff)
+	}
+	if diff := cmp.Diff([]core.KeyValue{key.New("link").String("abcd")}, got.Links[0].Attributes); diff != "" {
+		t.Errorf("link attributes: -want +got %s", diff)
+	}
+	if got, want := got.TruncatedAttributeValueCount, 6; got != want {
+		t.Errorf("TruncatedAttributeValueCount: got %d, want %d", got, want)
+	}
+}
+
+func TestEventAndLinkAttributeLimits(t *testing.T) {
+	spans := make(exporter)
+	p := NewTracerProvider(
+		WithConfig(Config{
+			DefaultSampler:        AlwaysSample(),
+			MaxAttributesPerEvent: 2,
+			MaxAttributesPerLink:  1,
+		}),
+		WithSyncer(spans),
+	)
+	k1, k2, k3 := key.New("key1").Int(1), key.New("key2").Int(2), key.New("key3").Int(3)
+
+	_, span := p.Tracer().Start(context.Background(), "span")
+	span.AddEvent(context.Background(), "three", k1, k2, k3)
+	span.AddEvent(context.Background(), "one", k1)
+	span.AddLink(apitrace.Link{SpanContext: core.SpanContext{TraceID: tid, SpanID: sid}, Attributes: []core.KeyValue{k1, k2}})
+	span.Link(core.SpanContext{TraceID: tid, SpanID: sid}, k1, k2, k3)
+	span.End()
+
+	got := spans["span"]
+	if diff := cmp.Diff([]core.KeyValue{k1, k2}, got.MessageEvents[0].Attributes); diff != "" {
+		t.Errorf("event attributes: -want +got %s", diff)
+	}
+	if diff := cmp.Diff([]core.KeyValue{k1}, got.MessageEvents[1].Attributes); diff != "" {
+		t.Errorf("event attributes: -want +got %s", diff)
+	}
+	for i, l := range got.Links {
+		if diff := cmp.Diff([]core.KeyValue{k1}, l.Attributes); diff != "" {
+			t.Errorf("link %d attributes: -want +got %s", i, diff)
+		}
+	}
+	if got.DroppedEventAttributeCount != 1 || got.DroppedLinkAttributeCount != 3 {
+		t.Errorf("dropped attributes: got %d per event and %d per link, want 1 and 3",
+			got.DroppedEventAttributeCount, got.DroppedLinkAttributeCount)
+	}
+}
+
 func TestEvents(t *testing.T) {
 	span := startSpan()
 	k1v1 := key.New("key1").String("value1")
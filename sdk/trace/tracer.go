@@ -19,12 +19,21 @@ import (
 
 	"go.opentelemetry.io/api/core"
 	apitrace "go.opentelemetry.io/api/trace"
+	"go.opentelemetry.io/sdk/resource"
 )
 
 type tracer struct {
-	name      string
-	component string
-	resources []core.KeyValue
+	provider *TracerProvider
+
+	// name and version identify the instrumentation library using the
+	// tracer. They are recorded on every span started by the tracer.
+	name    string
+	version string
+
+	// service is the service name set with WithService. It is added to
+	// resources as the service.name attribute.
+	service   string
+	resources *resource.Resource
 }
 
 var _ apitrace.Tracer = &tracer{}
@@ -54,16 +63,17 @@ func (tr *tracer) Start(ctx context.Context, name string, o ...apitrace.SpanOpti
 		if p := apitrace.CurrentSpan(ctx); p != nil {
 			if sdkSpan, ok := p.(*span); ok {
 				sdkSpan.addChild()
-				parent = sdkSpan.spanContext
+				parent = sdkSpan.SpanContext()
 			}
 		}
 	}
 
-	span := startSpanInternal(name, parent, remoteParent, opts)
-	span.tracer = tr
+	span := startSpanInternal(tr, name, parent, remoteParent, opts)
 
-	if span.IsRecordingEvents() {
-		sps, _ := spanProcessors.Load().(spanProcessorMap)
+	// Processors are notified of spans with a deferred sampling decision
+	// once it is made.
+	if span.IsRecordingEvents() && span.pending == nil {
+		sps, _ := tr.provider.spanProcessors.Load().(spanProcessorMap)
 		for sp := range sps {
 			sp.OnStart(span.data)
 		}
@@ -85,19 +95,44 @@ func (tr *tracer) WithSpan(ctx context.Context, name string, body func(ctx conte
 	return nil
 }
 
+// resource returns the resource of spans started by the tracer. Resources
+// set with WithResources take precedence over the configured resource.
+func (tr *tracer) resource(cfg *Config) *resource.Resource {
+	return resource.Merge(tr.resources, cfg.Resource)
+}
+
+// WithService returns a copy of the tracer whose spans have the given
+// service name as the service.name resource attribute.
 func (tr *tracer) WithService(name string) apitrace.Tracer {
-	tr.name = name
-	return tr
+	t := *tr
+	t.service = name
+	t.resources = t.withService(tr.resources)
+	return &t
 }
 
-// WithResources does nothing and returns noop implementation of apitrace.Tracer.
+// WithResources returns a copy of the tracer with the given resources. A
+// service name set with WithService takes precedence over a service.name
+// attribute among them.
 func (tr *tracer) WithResources(res ...core.KeyValue) apitrace.Tracer {
-	tr.resources = res
-	return tr
+	t := *tr
+	t.resources = t.withService(resource.New(res...))
+	return &t
+}
+
+// withService adds the service name of the tracer to res.
+func (tr *tracer) withService(res *resource.Resource) *resource.Resource {
+	if tr.service == "" {
+		return res
+	}
+	return resource.Merge(resource.New(resource.ServiceName.String(tr.service)), res)
 }
 
-// WithComponent does nothing and returns noop implementation of apitrace.Tracer.
+// WithComponent returns a copy of the tracer with the given component name.
+// The component names the instrumentation library using the tracer, like
+// the name passed to TracerProvider.GetTracer, and is recorded as the
+// TracerName of its spans.
 func (tr *tracer) WithComponent(component string) apitrace.Tracer {
-	tr.component = component
-	return tr
+	t := *tr
+	t.name = component
+	return &t
 }